@@ -38,8 +38,9 @@ func Run() error {
 
 	// Handle group messages
 	bot.MessageHandler = handlers.HandleGroupMessage
-	services.StartDailyWatchlistPush(bot)
-	services.StartIntervalWatchlistPush(bot)
+	services.StartStockScheduler(bot)
+	services.StartScheduledMessages()
+	services.StartQuoteRecorder()
 
 	// Block until exit
 	bot.Block()
@@ -28,6 +28,11 @@ func HandleGroupMessage(msg *openwechat.Message) {
 		services.HandleStockCommand(msg)
 	}
 
+	// 处理定时消息指令
+	if strings.HasPrefix(msg.Content, "定时") {
+		services.HandleScheduledMessageCommand(msg)
+	}
+
 	// 处理大盘查询，当输入牛来了，或者牛跑了，或者牛回速归，牛死速跑，则发送大盘概览
 	if strings.Contains(msg.Content, "牛来了") || strings.Contains(msg.Content, "牛跑了") || strings.Contains(msg.Content, "牛回速归") || strings.Contains(msg.Content, "牛死速跑") {
 		services.HandleMarketOverview(msg)
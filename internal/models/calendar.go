@@ -0,0 +1,15 @@
+package models
+
+// TradingCalendar A 股交易日历：休市日和提前收盘的半日市，周末默认休市无需列出
+type TradingCalendar struct {
+	Version  int           `json:"version"`
+	Holidays []CalendarDay `json:"holidays"`
+	HalfDays []CalendarDay `json:"half_days"`
+}
+
+// CalendarDay 日历中的特殊日期
+type CalendarDay struct {
+	Date  string `json:"date"`            // 日期，如 2024-10-01
+	Name  string `json:"name,omitempty"`  // 说明，如 国庆节
+	Close string `json:"close,omitempty"` // 半日市的收盘时间，如 11:30
+}
@@ -0,0 +1,28 @@
+package models
+
+// MinutePoint 分时数据中的一分钟
+type MinutePoint struct {
+	Time     string  // 时间，如 0931
+	Price    float64 // 该分钟最后成交价
+	AvgPrice float64 // 截至该分钟的成交均价
+	Volume   int64   // 该分钟成交量（股）
+}
+
+// MinuteSeries 单只股票当日的分时数据
+type MinuteSeries struct {
+	Code      string        // 股票代码
+	Name      string        // 股票名称
+	Date      string        // 交易日期，如 2024-01-05
+	PrevClose float64       // 昨收价
+	Points    []MinutePoint // 按时间排列的分时点
+}
+
+// KLineBar 一根 K 线
+type KLineBar struct {
+	Date   string  // 日期，周线、月线为该周期最后一个交易日
+	Open   float64 // 开盘价
+	Close  float64 // 收盘价
+	High   float64 // 最高价
+	Low    float64 // 最低价
+	Volume int64   // 成交量（股）
+}
@@ -0,0 +1,11 @@
+package models
+
+// QuoteSnapshot 行情记录器保存的一条快照，按行追加到每日的 jsonl 文件中
+type QuoteSnapshot struct {
+	RecordedAt string  `json:"t"`  // 记录时间，RFC3339
+	QuoteTime  string  `json:"qt"` // 行情自带的日期时间
+	Code       string  `json:"code"`
+	Price      float64 `json:"price"`
+	Volume     int64   `json:"volume"` // 当日累计成交量（股）
+	Amount     float64 `json:"amount"` // 当日累计成交额
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// PushState 推送记录，保存在关注列表旁边，重启后据此避免重复推送或立即补推
+type PushState struct {
+	Version        int                  `json:"version"`
+	DailyPushed    map[string]string    `json:"daily_pushed"`    // 群 ID|推送时间 -> 最近一次推送的日期
+	IntervalPushed map[string]time.Time `json:"interval_pushed"` // 群 ID|代码 -> 最近一次定时提醒的时间
+}
@@ -0,0 +1,22 @@
+package models
+
+// ScheduledMessageStore 各群的定时消息
+type ScheduledMessageStore struct {
+	Version int                            `json:"version"`
+	NextID  int                            `json:"next_id"`
+	Groups  map[string][]*ScheduledMessage `json:"groups"`
+	// Admins 各群可以管理定时消息的群管理员（群成员 UserName），群主无需列出
+	Admins map[string][]string `json:"admins,omitempty"`
+}
+
+// ScheduledMessage 一条定时消息，重复方式为 daily、weekday、trading_day 或 once
+type ScheduledMessage struct {
+	ID        int    `json:"id"`
+	Repeat    string `json:"repeat"`
+	Date      string `json:"date,omitempty"` // 只发一次时的日期，如 2026-10-20
+	Time      string `json:"time"`           // 发送时间，如 09:00
+	Kind      string `json:"kind"`           // text 或 image
+	Content   string `json:"content"`        // 文字内容或图片地址
+	CreatedBy string `json:"created_by"`
+	CreatedAt string `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+// SecurityMaster 本地证券代码表，用于离线识别代码和按名称搜索
+type SecurityMaster struct {
+	Version    int         `json:"version"`
+	Source     string      `json:"source"`
+	UpdatedAt  string      `json:"updated_at"`
+	Securities []*Security `json:"securities"`
+}
+
+// Security 单只证券的基础信息
+type Security struct {
+	Code     string `json:"code"`     // 标准代码，如 sh600519
+	Name     string `json:"name"`     // 证券简称
+	Exchange string `json:"exchange"` // 交易所：sh/sz/bj
+	Type     string `json:"type"`     // 品种：stock/index/etf
+	Status   string `json:"status"`   // 上市状态：listed/delisted
+	Pinyin   string `json:"pinyin"`   // 简称拼音首字母
+}
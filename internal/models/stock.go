@@ -2,11 +2,37 @@ package models
 
 // StockData 股票数据结构
 type StockData struct {
-	Name      string  // 股票名称
-	Code      string  // 股票代码
-	Price     float64 // 当前价格
-	Change    float64 // 涨跌额
-	ChangePct float64 // 涨跌幅
-	High      float64 // 最高价
-	Low       float64 // 最低价
+	Name      string        // 股票名称
+	Code      string        // 股票代码
+	Price     float64       // 当前价格
+	Change    float64       // 涨跌额
+	ChangePct float64       // 涨跌幅
+	High      float64       // 最高价
+	Low       float64       // 最低价
+	Open      float64       // 开盘价
+	PrevClose float64       // 昨收价
+	Volume    int64         // 成交量（股）
+	Amount    float64       // 成交额（元）
+	Bids      []OrderLevel  // 买一至买五
+	Asks      []OrderLevel  // 卖一至卖五
+	Date      string        // 行情日期
+	Time      string        // 行情时间
+	Currency  string        // 计价货币，如 CNY、HKD
+	Status    TradingStatus // 交易状态
+}
+
+// TradingStatus 行情对应的交易状态
+type TradingStatus string
+
+const (
+	StatusNormal    TradingStatus = "normal"     // 正常交易
+	StatusSuspended TradingStatus = "suspended"  // 停牌
+	StatusNotOpened TradingStatus = "not_opened" // 未开盘，当日尚无成交
+	StatusDelisted  TradingStatus = "delisted"   // 退市
+)
+
+// OrderLevel 盘口单档报价
+type OrderLevel struct {
+	Price  float64 // 报价
+	Volume int64   // 挂单量（股）
 }
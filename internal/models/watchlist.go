@@ -8,14 +8,29 @@ type WatchlistStore struct {
 
 // GroupWatchlist represents a group's watchlist and subscription settings.
 type GroupWatchlist struct {
-	GroupID        string         `json:"group_id"`
-	GroupName      string         `json:"group_name"`
-	Stocks         []string       `json:"stocks"`
-	Subscribed     bool           `json:"subscribed"`
-	StockIntervals map[string]int `json:"stock_intervals"`
-	Enabled        bool           `json:"enabled"`
-	DefaultLimit   int            `json:"default_limit"`
-	WindowMinutes  int            `json:"window_minutes"`
-	UserLimits     map[string]int `json:"user_limits"`
-	UpdatedAt      string         `json:"updated_at"`
+	GroupID        string          `json:"group_id"`
+	GroupName      string          `json:"group_name"`
+	Stocks         []string        `json:"stocks"`
+	Subscribed     bool            `json:"subscribed"`
+	StockIntervals map[string]int  `json:"stock_intervals"`
+	IntervalAllDay map[string]bool `json:"interval_all_day"` // 全天提醒的代码，其余只在交易时段提醒
+	Enabled        bool            `json:"enabled"`
+	DefaultLimit   int             `json:"default_limit"`
+	WindowMinutes  int             `json:"window_minutes"`
+	UserLimits     map[string]int  `json:"user_limits"`
+	Indices        []MarketIndex   `json:"indices"`
+	PushSlots      []PushSlot      `json:"push_slots"`
+	UpdatedAt      string          `json:"updated_at"`
+}
+
+// MarketIndex is an index shown in a group's market header.
+type MarketIndex struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// PushSlot is a daily report time; its name is used as the report title.
+type PushSlot struct {
+	Time string `json:"time"` // 15:05
+	Name string `json:"name"`
 }
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"html/template"
+	"strings"
+)
+
+// 分时图和 K 线图共用的画布尺寸，SVG 宽度为图片宽度减去左右留白
+const (
+	chartImageWidth   = 960
+	chartImageHeight  = 720
+	chartWidth        = 880
+	chartPriceHeight  = 360
+	chartVolumeHeight = 110
+	chartGap          = 36
+	chartPaddingLeft  = 70
+	chartPaddingRight = 70
+	chartPaddingTop   = 10
+)
+
+type chartLegendView struct {
+	Label string
+	Class string
+}
+
+type chartPageView struct {
+	Name      string
+	Code      string
+	Title     string
+	Price     string
+	Delta     string
+	Class     string
+	Legend    []chartLegendView
+	Chart     template.HTML
+	Timestamp string
+}
+
+func renderChartHTMLImage(view chartPageView) ([]byte, error) {
+	tpl, err := template.New("chart").Parse(chartHTMLTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var builder strings.Builder
+	if err := tpl.Execute(&builder, view); err != nil {
+		return nil, err
+	}
+	return renderHTMLToPNG(builder.String(), chartImageWidth, chartImageHeight)
+}
+
+const chartHTMLTemplate = `<!DOCTYPE html>
+<html lang="zh">
+<head>
+  <meta charset="UTF-8" />
+  <style>
+    :root {
+      --bg: #ffffff;
+      --text: #1f1f1f;
+      --muted: #6f6f6f;
+      --line: #f0f0f0;
+      --up: #d83a3a;
+      --down: #1ca05c;
+      --flat: #8f8f8f;
+      --price: #2f6fde;
+      --avg: #f0a020;
+      --ma5: #f0a020;
+      --ma10: #2f6fde;
+      --ma20: #b04fd8;
+    }
+    * { box-sizing: border-box; }
+    body {
+      margin: 0;
+      background: var(--bg);
+      font-family: "Maple Mono NF CN", "PingFang SC", "PingFang TC", "Microsoft Yahei", sans-serif;
+      color: var(--text);
+    }
+    .container {
+      width: 960px;
+      padding: 28px 40px 28px 40px;
+    }
+    .header {
+      display: flex;
+      align-items: baseline;
+      gap: 18px;
+      font-variant-numeric: tabular-nums;
+    }
+    .name { font-size: 28px; font-weight: 600; }
+    .code { font-size: 18px; color: var(--muted); }
+    .price { font-size: 30px; font-weight: 600; }
+    .delta { font-size: 20px; }
+    .legend {
+      margin: 10px 0 14px 0;
+      font-size: 14px;
+      color: var(--muted);
+      font-variant-numeric: tabular-nums;
+    }
+    .legend span { margin-right: 18px; }
+    .legend .price-key { color: var(--price); }
+    .legend .avg-key { color: var(--avg); }
+    .legend .ma5-key { color: var(--ma5); }
+    .legend .ma10-key { color: var(--ma10); }
+    .legend .ma20-key { color: var(--ma20); }
+    svg text { fill: var(--muted); font-variant-numeric: tabular-nums; }
+    svg text.up { fill: var(--up); }
+    svg text.down { fill: var(--down); }
+    svg .grid { stroke: var(--line); stroke-width: 1; }
+    svg .base { stroke: var(--flat); stroke-width: 1; stroke-dasharray: 4 4; }
+    svg .price { fill: none; stroke: var(--price); stroke-width: 1.6; }
+    svg .avg { fill: none; stroke: var(--avg); stroke-width: 1.2; }
+    svg .ma5 { fill: none; stroke: var(--ma5); stroke-width: 1.2; }
+    svg .ma10 { fill: none; stroke: var(--ma10); stroke-width: 1.2; }
+    svg .ma20 { fill: none; stroke: var(--ma20); stroke-width: 1.2; }
+    svg .bar-up { fill: var(--up); }
+    svg .bar-down { fill: var(--down); }
+    svg .bar-flat { fill: var(--flat); }
+    svg .wick-up { stroke: var(--up); stroke-width: 1; }
+    svg .wick-down { stroke: var(--down); stroke-width: 1; }
+    svg .wick-flat { stroke: var(--flat); stroke-width: 1; }
+    .up { color: var(--up); }
+    .down { color: var(--down); }
+    .flat { color: var(--flat); }
+    .footer {
+      margin-top: 10px;
+      font-size: 14px;
+      color: var(--muted);
+    }
+  </style>
+</head>
+<body>
+  <div class="container">
+    <div class="header">
+      <span class="name">{{.Name}}</span>
+      <span class="code">{{.Code}} {{.Title}}</span>
+      <span class="price {{.Class}}">{{.Price}}</span>
+      <span class="delta {{.Class}}">{{.Delta}}</span>
+    </div>
+    <div class="legend">
+      {{range .Legend}}<span class="{{.Class}}">{{.Label}}</span>{{end}}
+    </div>
+    {{.Chart}}
+    <div class="footer">更新时间：{{.Timestamp}}</div>
+  </div>
+</body>
+</html>`
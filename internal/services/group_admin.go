@@ -0,0 +1,237 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/eatmoreapple/openwechat"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 群主从网页版群详情的 ChatRoomOwner 字段读取，openwechat 的 User 没有解析这个字段，需要直接请求；
+// 网页版协议不提供群管理员，群管理员由群主或超管用 定时管理员 指令指定
+type groupOwnerEntry struct {
+	owner     string
+	fetchedAt time.Time
+}
+
+var groupOwnerMu sync.Mutex
+var groupOwners = make(map[string]groupOwnerEntry)
+
+// groupOwner 返回群主的 UserName，缓存 groupCacheTTL，请求失败时沿用上次的结果
+func groupOwner(bot *openwechat.Bot, groupID string) string {
+	groupOwnerMu.Lock()
+	entry, ok := groupOwners[groupID]
+	groupOwnerMu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < groupCacheTTL {
+		return entry.owner
+	}
+	owner, err := fetchGroupOwner(bot, groupID)
+	if err != nil {
+		fmt.Println("fetch group owner:", err)
+		return entry.owner
+	}
+	groupOwnerMu.Lock()
+	groupOwners[groupID] = groupOwnerEntry{owner: owner, fetchedAt: time.Now()}
+	groupOwnerMu.Unlock()
+	return owner
+}
+
+func fetchGroupOwner(bot *openwechat.Bot, groupID string) (string, error) {
+	if bot == nil || bot.Caller == nil || bot.Storage == nil || bot.Storage.Request == nil {
+		return "", fmt.Errorf("机器人未登录")
+	}
+	members := openwechat.Members{&openwechat.User{UserName: groupID}}
+	resp, err := bot.Caller.Client.WebWxBatchGetContact(bot.Context(), members, bot.Storage.Request)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		BaseResponse openwechat.BaseResponse
+		ContactList  []struct {
+			UserName      string
+			ChatRoomOwner string
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if err := result.BaseResponse.Err(); err != nil {
+		return "", err
+	}
+	for _, contact := range result.ContactList {
+		if contact.UserName == groupID {
+			return contact.ChatRoomOwner, nil
+		}
+	}
+	return "", fmt.Errorf("未找到群 %s", groupID)
+}
+
+func isGroupOwner(msg *openwechat.Message, groupID, userName string) bool {
+	owner := groupOwner(msg.Bot(), groupID)
+	return owner != "" && owner == userName
+}
+
+func isScheduledMessageAdmin(groupID, userName string) bool {
+	store, err := loadScheduledMessageStore()
+	if err != nil {
+		return false
+	}
+	for _, admin := range store.Admins[groupID] {
+		if admin == userName {
+			return true
+		}
+	}
+	return false
+}
+
+// canManageScheduledMessages 群主、群管理员可以管理本群的定时消息，超管可以管理所有群
+func canManageScheduledMessages(msg *openwechat.Message, groupID, userName string) bool {
+	return userName != "" && (superAdmins[userName] || isGroupOwner(msg, groupID, userName) || isScheduledMessageAdmin(groupID, userName))
+}
+
+func requireScheduledMessageAdmin(msg *openwechat.Message, groupID string) bool {
+	if canManageScheduledMessages(msg, groupID, getSenderUserName(msg)) {
+		return true
+	}
+	msg.ReplyText("仅群主、群管理员或超管可设置定时消息")
+	return false
+}
+
+// groupMembers 读取群成员，失败时只在日志里记录原因
+func groupMembers(msg *openwechat.Message) (openwechat.Members, bool) {
+	sender, err := msg.Sender()
+	if err != nil {
+		fmt.Println("load group members:", err)
+		msg.ReplyText("读取群成员失败，请稍后再试")
+		return nil, false
+	}
+	members, err := (&openwechat.Group{User: sender}).Members()
+	if err != nil {
+		fmt.Println("load group members:", err)
+		msg.ReplyText("读取群成员失败，请稍后再试")
+		return nil, false
+	}
+	return members, true
+}
+
+// handleScheduledMessageAdmins 定时管理员：查看、添加、删除本群的群管理员，群主、群管理员和超管可以查看，
+// 只有群主和超管可以修改。先检查权限再读取群成员，避免任何人发指令都触发一次成员请求
+func handleScheduledMessageAdmins(msg *openwechat.Message, groupID, args string) {
+	usage := "用法：定时管理员 / 定时管理员 添加 @成员 / 定时管理员 删除 @成员"
+	// @成员 后面跟的是 \u2005，strings.Fields 会把它当作空白拆开
+	fields := strings.Fields(args)
+	userName := getSenderUserName(msg)
+	if len(fields) == 0 {
+		if !canManageScheduledMessages(msg, groupID, userName) {
+			msg.ReplyText("仅群主、群管理员或超管可查看群管理员")
+			return
+		}
+		store, err := loadScheduledMessageStore()
+		if err != nil {
+			msg.ReplyText(fmt.Sprintf("读取定时消息失败：%v", err))
+			return
+		}
+		if len(store.Admins[groupID]) == 0 {
+			msg.ReplyText("本群没有指定群管理员，群主和超管可以设置定时消息\n" + usage)
+			return
+		}
+		members, ok := groupMembers(msg)
+		if !ok {
+			return
+		}
+		names := make([]string, 0, len(store.Admins[groupID]))
+		for _, admin := range store.Admins[groupID] {
+			names = append(names, memberDisplayName(members, admin))
+		}
+		msg.ReplyText(fmt.Sprintf("本群群管理员：%s\n%s", strings.Join(names, "、"), usage))
+		return
+	}
+	if userName == "" || (!superAdmins[userName] && !isGroupOwner(msg, groupID, userName)) {
+		msg.ReplyText("仅群主或超管可修改群管理员")
+		return
+	}
+	if len(fields) < 2 || (fields[0] != "添加" && fields[0] != "删除") {
+		msg.ReplyText(usage)
+		return
+	}
+	members, ok := groupMembers(msg)
+	if !ok {
+		return
+	}
+	var targets []string
+	var unknown []string
+	for _, field := range fields[1:] {
+		name := strings.TrimPrefix(field, "@")
+		found := members.Search(1, func(user *openwechat.User) bool {
+			return user.DisplayName == name || user.NickName == name
+		})
+		if found.Count() == 0 {
+			unknown = append(unknown, name)
+			continue
+		}
+		targets = append(targets, found.First().UserName)
+	}
+	if len(unknown) > 0 {
+		msg.ReplyText(fmt.Sprintf("本群没有成员：%s，未做修改", strings.Join(unknown, " ")))
+		return
+	}
+	if err := updateScheduledMessageAdmins(groupID, targets, fields[0] == "添加"); err != nil {
+		msg.ReplyText(fmt.Sprintf("设置失败：%v", err))
+		return
+	}
+	names := make([]string, 0, len(targets))
+	for _, target := range targets {
+		names = append(names, memberDisplayName(members, target))
+	}
+	msg.ReplyText(fmt.Sprintf("已%s群管理员：%s", fields[0], strings.Join(names, "、")))
+}
+
+func updateScheduledMessageAdmins(groupID string, userNames []string, add bool) error {
+	scheduledMessageMu.Lock()
+	defer scheduledMessageMu.Unlock()
+	store, err := loadScheduledMessageStore()
+	if err != nil {
+		return err
+	}
+	if store.Admins == nil {
+		store.Admins = make(map[string][]string)
+	}
+	admins := store.Admins[groupID]
+	for _, userName := range userNames {
+		index := -1
+		for i, admin := range admins {
+			if admin == userName {
+				index = i
+				break
+			}
+		}
+		switch {
+		case add && index < 0:
+			admins = append(admins, userName)
+		case !add && index >= 0:
+			admins = append(admins[:index], admins[index+1:]...)
+		}
+	}
+	if len(admins) == 0 {
+		delete(store.Admins, groupID)
+	} else {
+		store.Admins[groupID] = admins
+	}
+	return saveScheduledMessageStore(store)
+}
+
+// memberDisplayName 优先显示群昵称，找不到成员时显示 UserName
+func memberDisplayName(members openwechat.Members, userName string) string {
+	found := members.SearchByUserName(1, userName)
+	if found.Count() == 0 {
+		return userName
+	}
+	member := found.First()
+	if member.DisplayName != "" {
+		return member.DisplayName
+	}
+	return member.NickName
+}
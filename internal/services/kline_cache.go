@@ -0,0 +1,133 @@
+package services
+
+import (
+	"github.com/luckfunc/golangBot/internal/models"
+	"sync"
+	"time"
+)
+
+// K 线缓存的刷新间隔：过期后只重新拉取最近几根并合并；数据为前复权，除权除息后历史价格会整体变化，
+// 所以每天至少整段重新请求一次，合并时发现重叠的旧 K 线价格变了也整段重新请求
+var klineCacheTTL = envDuration("STOCK_KLINE_CACHE_TTL", 5*time.Minute)
+
+// 刷新时拉取的最近 K 线数量，覆盖节假日前后最新一根的变化
+const klineRefreshBars = 5
+
+// 单次最多请求的 K 线数量
+const maxKLineBars = 640
+
+var sharedKLineCache = newKLineCache(klineCacheTTL)
+
+type klineCacheEntry struct {
+	bars      []models.KLineBar
+	complete  bool // 数据源返回的数量少于请求数量，说明已到上市首日
+	fetchedAt time.Time
+	loadedAt  time.Time // 最近一次整段请求的时间
+}
+
+type klineCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*klineCacheEntry
+}
+
+func newKLineCache(ttl time.Duration) *klineCache {
+	return &klineCache{
+		ttl:     ttl,
+		entries: make(map[string]*klineCacheEntry),
+	}
+}
+
+// get 返回最近 count 根 K 线：缓存足够且未过期直接使用，过期只补拉最近几根，缓存不足时整段重新请求
+func (c *klineCache) get(code, period string, count int, fetch func(count int) ([]models.KLineBar, error)) ([]models.KLineBar, error) {
+	key := code + ":" + period
+	now := time.Now()
+
+	c.mu.Lock()
+	entry := c.entries[key]
+	var covered, fresh bool
+	if entry != nil {
+		covered = (entry.complete || len(entry.bars) >= count) && sameDay(entry.loadedAt, now)
+		fresh = now.Sub(entry.fetchedAt) < c.ttl
+	}
+	if covered && fresh {
+		bars := tailKLines(entry.bars, count)
+		c.mu.Unlock()
+		return bars, nil
+	}
+	c.mu.Unlock()
+
+	if covered {
+		if recent, err := fetch(klineRefreshBars); err == nil {
+			c.mu.Lock()
+			merged, ok := mergeKLines(entry.bars, recent)
+			if ok {
+				entry.bars = merged
+				entry.fetchedAt = now
+			}
+			c.mu.Unlock()
+			if ok {
+				return tailKLines(merged, count), nil
+			}
+			// 最近的 K 线与缓存接不上（如长期停牌）或复权价格有变化，整段重新请求
+		}
+	}
+
+	bars, err := fetch(count)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[key] = &klineCacheEntry{bars: bars, complete: len(bars) < count, fetchedAt: now, loadedAt: now}
+	c.mu.Unlock()
+	return tailKLines(bars, count), nil
+}
+
+// mergeKLines 用最近的 K 线覆盖缓存中相同日期及之后的部分；
+// 周线、月线的最后一根会随交易日更新日期，因此按最早一根新数据所在位置截断。
+// 最早一根新数据是已走完的 K 线，价格与缓存不同说明发生了除权除息，返回 false
+func mergeKLines(cached, recent []models.KLineBar) ([]models.KLineBar, bool) {
+	if len(recent) == 0 {
+		return cached, true
+	}
+	first := recent[0].Date
+	for i := len(cached) - 1; i >= 0; i-- {
+		if cached[i].Date == first {
+			if len(recent) > 1 && !samePrices(cached[i], recent[0]) {
+				return nil, false
+			}
+			merged := make([]models.KLineBar, 0, i+len(recent))
+			merged = append(merged, cached[:i]...)
+			return append(merged, recent...), true
+		}
+		if cached[i].Date < first {
+			break
+		}
+	}
+	return nil, false
+}
+
+func samePrices(a, b models.KLineBar) bool {
+	return a.Open == b.Open && a.Close == b.Close && a.High == b.High && a.Low == b.Low
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
+
+func tailKLines(bars []models.KLineBar, count int) []models.KLineBar {
+	if len(bars) > count {
+		bars = bars[len(bars)-count:]
+	}
+	return append([]models.KLineBar(nil), bars...)
+}
+
+// fetchKLines 经由缓存获取最近 count 根 K 线
+func fetchKLines(code, period string, count int) ([]models.KLineBar, error) {
+	if count > maxKLineBars {
+		count = maxKLineBars
+	}
+	return sharedKLineCache.get(code, period, count, func(count int) ([]models.KLineBar, error) {
+		return klineProvider.KLines(code, period, count)
+	})
+}
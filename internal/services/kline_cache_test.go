@@ -0,0 +1,50 @@
+package services
+
+import (
+	"fmt"
+	"github.com/luckfunc/golangBot/internal/models"
+	"testing"
+)
+
+func klineSeries(days int, adjust float64) []models.KLineBar {
+	bars := make([]models.KLineBar, days)
+	for i := range bars {
+		price := float64(10+i) - adjust
+		bars[i] = models.KLineBar{Date: fmt.Sprintf("2026-09-%02d", i+1), Open: price, Close: price, High: price, Low: price}
+	}
+	return bars
+}
+
+func TestKLineCacheRefetchesAfterAdjustment(t *testing.T) {
+	cache := newKLineCache(0)
+	series := klineSeries(20, 0)
+	var requests []int
+	fetch := func(count int) ([]models.KLineBar, error) {
+		requests = append(requests, count)
+		return tailKLines(series, count), nil
+	}
+
+	if _, err := cache.get("sh600519", klineDay, 20, fetch); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.get("sh600519", klineDay, 20, fetch); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{20, klineRefreshBars}; fmt.Sprint(requests) != fmt.Sprint(want) {
+		t.Fatalf("requests = %v, want %v", requests, want)
+	}
+
+	// 除权后所有历史价格下调，只补拉最近几根会接不上，需要整段重新请求
+	series = klineSeries(20, 1)
+	requests = nil
+	bars, err := cache.get("sh600519", klineDay, 20, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{klineRefreshBars, 20}; fmt.Sprint(requests) != fmt.Sprint(want) {
+		t.Fatalf("requests after adjustment = %v, want %v", requests, want)
+	}
+	if bars[0].Close != series[0].Close {
+		t.Fatalf("first bar close = %v, want adjusted %v", bars[0].Close, series[0].Close)
+	}
+}
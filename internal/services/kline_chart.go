@@ -0,0 +1,274 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/eatmoreapple/openwechat"
+	"github.com/luckfunc/golangBot/internal/models"
+	"html/template"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultKLineCount = 60
+	minKLineCount     = 10
+	maxKLineCount     = 250
+)
+
+// 均线周期，请求 K 线时多取最长周期减一根，保证第一根就有均线
+var klineMAPeriods = []int{5, 10, 20}
+
+var klinePeriodNames = map[string]string{
+	klineDay:   "日K",
+	klineWeek:  "周K",
+	klineMonth: "月K",
+}
+
+// parseKLinePeriod 识别周期关键字：日K/周K/月K，也接受 日/周/月
+func parseKLinePeriod(field string) (string, bool) {
+	switch strings.ToUpper(field) {
+	case "日K", "日", "DAY":
+		return klineDay, true
+	case "周K", "周", "WEEK":
+		return klineWeek, true
+	case "月K", "月", "MONTH":
+		return klineMonth, true
+	}
+	return "", false
+}
+
+func handleKLineChart(msg *openwechat.Message, args string) {
+	period := klineDay
+	count := defaultKLineCount
+	var rest []string
+	for _, field := range strings.Fields(args) {
+		if value, ok := parseKLinePeriod(field); ok {
+			period = value
+			continue
+		}
+		// 3 位以内的数字是根数，5 位、6 位数字是股票代码
+		if len(field) <= 3 && isNumeric(field) {
+			count, _ = strconv.Atoi(field)
+			continue
+		}
+		rest = append(rest, field)
+	}
+	if count < minKLineCount || count > maxKLineCount {
+		msg.ReplyText(fmt.Sprintf("K 线根数需在 %d 到 %d 之间", minKLineCount, maxKLineCount))
+		return
+	}
+	content := strings.Join(rest, " ")
+	lookup := extractStockLookup(content)
+	if len(lookup.Candidates) > 0 {
+		msg.ReplyText(formatCandidatesReply(lookup, "股票K线"))
+		return
+	}
+	if lookup.Code == "" {
+		for _, field := range rest {
+			if suggestions := suggestSecurities(field); len(suggestions) > 0 {
+				msg.ReplyText(formatUnresolvedReply(field, suggestions))
+				return
+			}
+		}
+		msg.ReplyText("用法：股票K线 600519 60（根数可省略，默认 60）\n周线、月线：股票K线 600519 周K / 股票K线 600519 120 月K")
+		return
+	}
+
+	maxPeriod := klineMAPeriods[len(klineMAPeriods)-1]
+	bars, err := fetchKLines(lookup.Code, period, count+maxPeriod-1)
+	if err != nil {
+		msg.ReplyText(fmt.Sprintf("获取K线数据失败：%v", err))
+		return
+	}
+	name := securityDisplayName(lookup.Code)
+	if name == "" {
+		if stock, err := getStockData(lookup.Code); err == nil {
+			name = stock.Name
+		}
+	}
+	image, err := renderKLineChartHTMLImage(lookup.Code, name, period, bars, count)
+	if err == nil {
+		_, _ = msg.ReplyImage(bytes.NewReader(image))
+		return
+	}
+	msg.ReplyText(fmt.Sprintf("生成图片失败：%v\n%s", err, formatKLineSummary(lookup.Code, name, period, bars, count)))
+}
+
+// movingAverage 收盘价的 n 周期简单均线，数据不足的位置为 0
+func movingAverage(bars []models.KLineBar, n int) []float64 {
+	out := make([]float64, len(bars))
+	sum := 0.0
+	for i, bar := range bars {
+		sum += bar.Close
+		if i >= n {
+			sum -= bars[i-n].Close
+		}
+		if i >= n-1 {
+			out[i] = sum / float64(n)
+		}
+	}
+	return out
+}
+
+// klineChange 最后一根相对前一根收盘的涨跌额和涨跌幅
+func klineChange(bars []models.KLineBar) (float64, float64) {
+	if len(bars) < 2 {
+		return 0, 0
+	}
+	last, prev := bars[len(bars)-1].Close, bars[len(bars)-2].Close
+	if prev == 0 {
+		return 0, 0
+	}
+	return last - prev, (last - prev) / prev * 100
+}
+
+// formatKLineSummary K 线图生成失败时的文字摘要
+func formatKLineSummary(code, name, period string, bars []models.KLineBar, count int) string {
+	shown := tailKLines(bars, count)
+	high, low := shown[0].High, shown[0].Low
+	for _, bar := range shown {
+		high = math.Max(high, bar.High)
+		low = math.Min(low, bar.Low)
+	}
+	last := bars[len(bars)-1]
+	change, pct := klineChange(bars)
+	lines := []string{
+		fmt.Sprintf("%s (%s) %s 最近 %d 根", name, code, klinePeriodNames[period], len(shown)),
+		fmt.Sprintf("收盘：%.2f（%+.2f %+.2f%%）%s", last.Close, change, pct, last.Date),
+		fmt.Sprintf("区间最高：%.2f  区间最低：%.2f", high, low),
+	}
+	var averages []string
+	for _, n := range klineMAPeriods {
+		if ma := movingAverage(bars, n); ma[len(ma)-1] > 0 {
+			averages = append(averages, fmt.Sprintf("MA%d %.2f", n, ma[len(ma)-1]))
+		}
+	}
+	if len(averages) > 0 {
+		lines = append(lines, strings.Join(averages, "  "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func renderKLineChartHTMLImage(code, name, period string, bars []models.KLineBar, count int) ([]byte, error) {
+	last := bars[len(bars)-1]
+	change, pct := klineChange(bars)
+	var legend []chartLegendView
+	for _, n := range klineMAPeriods {
+		ma := movingAverage(bars, n)
+		label := fmt.Sprintf("MA%d --", n)
+		if value := ma[len(ma)-1]; value > 0 {
+			label = fmt.Sprintf("MA%d %.2f", n, value)
+		}
+		legend = append(legend, chartLegendView{Label: label, Class: fmt.Sprintf("ma%d-key", n)})
+	}
+	return renderChartHTMLImage(chartPageView{
+		Name:      name,
+		Code:      code,
+		Title:     klinePeriodNames[period],
+		Price:     fmt.Sprintf("%.2f", last.Close),
+		Delta:     fmt.Sprintf("%+.2f  %+.2f%%", change, pct),
+		Class:     trendClass(change),
+		Legend:    legend,
+		Chart:     template.HTML(buildKLineChartSVG(code, bars, count)),
+		Timestamp: last.Date,
+	})
+}
+
+// buildKLineChartSVG 生成蜡烛图：上方为 K 线和均线，下方为成交量柱；
+// bars 包含计算均线所需的更早数据，只画最后 count 根
+func buildKLineChartSVG(code string, bars []models.KLineBar, count int) string {
+	averages := make([][]float64, len(klineMAPeriods))
+	for i, n := range klineMAPeriods {
+		averages[i] = movingAverage(bars, n)
+	}
+	start := 0
+	if len(bars) > count {
+		start = len(bars) - count
+	}
+	shown := bars[start:]
+	plotWidth := float64(chartWidth - chartPaddingLeft - chartPaddingRight)
+	slot := plotWidth / float64(len(shown))
+
+	high, low := shown[0].High, shown[0].Low
+	var maxVolume int64
+	for i, bar := range shown {
+		high = math.Max(high, bar.High)
+		low = math.Min(low, bar.Low)
+		for _, ma := range averages {
+			if value := ma[start+i]; value > 0 {
+				high = math.Max(high, value)
+				low = math.Min(low, value)
+			}
+		}
+		if bar.Volume > maxVolume {
+			maxVolume = bar.Volume
+		}
+	}
+	padding := (high - low) * 0.05
+	if padding == 0 {
+		padding = high * 0.01
+	}
+	high += padding
+	low -= padding
+
+	x := func(i int) float64 {
+		return chartPaddingLeft + (float64(i)+0.5)*slot
+	}
+	y := func(price float64) float64 {
+		return chartPaddingTop + (high-price)/(high-low)*chartPriceHeight
+	}
+	volumeTop := float64(chartPaddingTop + chartPriceHeight + chartGap)
+	height := chartPaddingTop + chartPriceHeight + chartGap + chartVolumeHeight + 4
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-size="13">`, chartWidth, height)
+
+	// 价格网格，左侧标价格
+	for i := 0; i <= 4; i++ {
+		price := high - float64(i)*(high-low)/4
+		lineY := y(price)
+		fmt.Fprintf(&svg, `<line class="grid" x1="%d" y1="%.1f" x2="%.1f" y2="%.1f"/>`, chartPaddingLeft, lineY, chartPaddingLeft+plotWidth, lineY)
+		fmt.Fprintf(&svg, `<text x="%d" y="%.1f" text-anchor="end">%.2f</text>`, chartPaddingLeft-8, lineY+4, price)
+	}
+	// 横轴日期：首、中、尾三根
+	for _, i := range []int{0, len(shown) / 2, len(shown) - 1} {
+		anchor := "middle"
+		if i == 0 {
+			anchor = "start"
+		} else if i == len(shown)-1 {
+			anchor = "end"
+		}
+		fmt.Fprintf(&svg, `<text x="%.1f" y="%.1f" text-anchor="%s">%s</text>`, x(i), volumeTop-12, anchor, shown[i].Date)
+	}
+
+	bodyWidth := math.Max(slot*0.7, 1)
+	for i, bar := range shown {
+		class := trendClass(bar.Close - bar.Open)
+		fmt.Fprintf(&svg, `<line class="wick-%s" x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f"/>`, class, x(i), y(bar.High), x(i), y(bar.Low))
+		top := y(math.Max(bar.Open, bar.Close))
+		bodyHeight := math.Max(y(math.Min(bar.Open, bar.Close))-top, 1)
+		fmt.Fprintf(&svg, `<rect class="bar-%s" x="%.1f" y="%.1f" width="%.1f" height="%.1f"/>`, class, x(i)-bodyWidth/2, top, bodyWidth, bodyHeight)
+		if maxVolume > 0 && bar.Volume > 0 {
+			barHeight := float64(bar.Volume) / float64(maxVolume) * chartVolumeHeight
+			fmt.Fprintf(&svg, `<rect class="bar-%s" x="%.1f" y="%.1f" width="%.1f" height="%.1f"/>`, class, x(i)-bodyWidth/2, volumeTop+chartVolumeHeight-barHeight, bodyWidth, barHeight)
+		}
+	}
+	fmt.Fprintf(&svg, `<line class="grid" x1="%d" y1="%.1f" x2="%.1f" y2="%.1f"/>`, chartPaddingLeft, volumeTop+chartVolumeHeight, chartPaddingLeft+plotWidth, volumeTop+chartVolumeHeight)
+	fmt.Fprintf(&svg, `<text x="%d" y="%.1f" text-anchor="end">%s</text>`, chartPaddingLeft-8, volumeTop+12, formatVolumeUnits(code, maxVolume))
+
+	for n, ma := range averages {
+		var points []string
+		for i := range shown {
+			if value := ma[start+i]; value > 0 {
+				points = append(points, fmt.Sprintf("%.1f,%.1f", x(i), y(value)))
+			}
+		}
+		if len(points) > 1 {
+			fmt.Fprintf(&svg, `<polyline class="ma%d" points="%s"/>`, klineMAPeriods[n], strings.Join(points, " "))
+		}
+	}
+	svg.WriteString(`</svg>`)
+	return svg.String()
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/luckfunc/golangBot/internal/models"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// K 线周期
+const (
+	klineDay   = "day"
+	klineWeek  = "week"
+	klineMonth = "month"
+)
+
+// KLineProvider 获取最近 count 根 K 线，按日期从早到晚排列
+type KLineProvider interface {
+	KLines(code, period string, count int) ([]models.KLineBar, error)
+}
+
+var klineProvider KLineProvider = NewTencentKLineProvider(envString("STOCK_KLINE_BASE_URL", defaultTencentMinuteBaseURL))
+
+// TencentKLineProvider 腾讯财经前复权 K 线接口 web.ifzq.gtimg.cn/appstock/app/fqkline/get
+type TencentKLineProvider struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewTencentKLineProvider 创建腾讯 K 线数据源，baseURL 为空时使用 web.ifzq.gtimg.cn
+func NewTencentKLineProvider(baseURL string) *TencentKLineProvider {
+	if baseURL == "" {
+		baseURL = defaultTencentMinuteBaseURL
+	}
+	return &TencentKLineProvider{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *TencentKLineProvider) KLines(code, period string, count int) ([]models.KLineBar, error) {
+	symbol := tencentSymbol(code)
+	endpoint := fmt.Sprintf("%s/appstock/app/fqkline/get?param=%s,%s,,,%d,qfq", p.BaseURL, symbol, period, count)
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tencent kline status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseTencentKLines(body, code, symbol, period)
+}
+
+// parseTencentKLines 解析 K 线响应：data.<代码>.qfqday 每项为 [日期, 开, 收, 高, 低, 成交量, ...]，
+// 指数没有复权数据，字段名为 day/week/month；A 股成交量单位为手
+func parseTencentKLines(body []byte, code, symbol, period string) ([]models.KLineBar, error) {
+	var payload struct {
+		Code int                                   `json:"code"`
+		Msg  string                                `json:"msg"`
+		Data map[string]map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	if payload.Code != 0 {
+		return nil, fmt.Errorf("tencent kline error: %s", payload.Msg)
+	}
+	entry := payload.Data[symbol]
+	raw, ok := entry["qfq"+period]
+	if !ok {
+		raw, ok = entry[period]
+	}
+	if !ok {
+		return nil, fmt.Errorf("no kline data for %s", code)
+	}
+	// 除权日的记录末尾会附带分红送转说明对象，按原始 JSON 逐项解析
+	var rows [][]json.RawMessage
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, err
+	}
+	volumeUnit := 1.0
+	if isAShareMarket(stockMarket(code)) {
+		volumeUnit = 100
+	}
+	bars := make([]models.KLineBar, 0, len(rows))
+	for _, row := range rows {
+		if len(row) < 6 {
+			continue
+		}
+		fields := make([]string, 6)
+		for i := range fields {
+			if err := json.Unmarshal(row[i], &fields[i]); err != nil {
+				fields[i] = ""
+			}
+		}
+		bars = append(bars, models.KLineBar{
+			Date:   fields[0],
+			Open:   parseFloatField(fields[1]),
+			Close:  parseFloatField(fields[2]),
+			High:   parseFloatField(fields[3]),
+			Low:    parseFloatField(fields[4]),
+			Volume: int64(parseFloatField(fields[5]) * volumeUnit),
+		})
+	}
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("no kline data for %s", code)
+	}
+	return bars, nil
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"fmt"
+	"github.com/eatmoreapple/openwechat"
+	"github.com/luckfunc/golangBot/internal/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxGroupIndices 每个群最多展示的指数个数，避免图片表头过长
+const maxGroupIndices = 6
+
+// 群未设置时展示的大盘指数
+var defaultMarketIndices = []models.MarketIndex{
+	{Code: "sh000001", Name: "上证"},
+	{Code: "sz399001", Name: "深证"},
+	{Code: "sz399006", Name: "创业板"},
+}
+
+// 常用指数的名称和简称，港股、美股指数不在本地代码表中，也通过这里识别
+var knownMarketIndices = map[string]models.MarketIndex{
+	"上证":     {Code: "sh000001", Name: "上证"},
+	"上证指数":   {Code: "sh000001", Name: "上证"},
+	"深证":     {Code: "sz399001", Name: "深证"},
+	"深证成指":   {Code: "sz399001", Name: "深证"},
+	"创业板":    {Code: "sz399006", Name: "创业板"},
+	"创业板指":   {Code: "sz399006", Name: "创业板"},
+	"沪深300":  {Code: "sh000300", Name: "沪深300"},
+	"上证50":   {Code: "sh000016", Name: "上证50"},
+	"科创50":   {Code: "sh000688", Name: "科创50"},
+	"中证500":  {Code: "sh000905", Name: "中证500"},
+	"中证1000": {Code: "sh000852", Name: "中证1000"},
+	"北证50":   {Code: "bj899050", Name: "北证50"},
+	"恒指":     {Code: "hkHSI", Name: "恒生指数"},
+	"恒生指数":   {Code: "hkHSI", Name: "恒生指数"},
+	"恒生科技":   {Code: "hkHSTECH", Name: "恒生科技"},
+	"国企指数":   {Code: "hkHSCEI", Name: "国企指数"},
+	"道指":     {Code: "gb_$dji", Name: "道琼斯"},
+	"道琼斯":    {Code: "gb_$dji", Name: "道琼斯"},
+	"纳指":     {Code: "gb_$ixic", Name: "纳斯达克"},
+	"纳斯达克":   {Code: "gb_$ixic", Name: "纳斯达克"},
+	"标普500":  {Code: "gb_$inx", Name: "标普500"},
+}
+
+// groupMarketIndices 返回群设置的指数列表，非群聊或未设置时使用默认列表
+func groupMarketIndices(groupID string) []models.MarketIndex {
+	if groupID == "" {
+		return defaultMarketIndices
+	}
+	store, err := loadWatchlistStore()
+	if err != nil {
+		return defaultMarketIndices
+	}
+	return marketIndicesOf(store.Groups[groupID])
+}
+
+func marketIndicesOf(group *models.GroupWatchlist) []models.MarketIndex {
+	if group == nil || len(group.Indices) == 0 {
+		return defaultMarketIndices
+	}
+	return group.Indices
+}
+
+// isMarketIndexCode 判断代码是否为指数：本地代码表中的指数或常用港美股指数
+func isMarketIndexCode(code string) bool {
+	if security := lookupSecurity(code); security != nil {
+		return security.Type == securityTypeIndex
+	}
+	for _, index := range knownMarketIndices {
+		if index.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// isIndexCodeRange 上证 000 开头、深证 399 开头的代码段都是指数
+func isIndexCodeRange(code string) bool {
+	return strings.HasPrefix(code, marketSH+"000") || strings.HasPrefix(code, marketSZ+"399")
+}
+
+// messageMarketIndices 返回消息所在群的指数列表
+func messageMarketIndices(msg *openwechat.Message) []models.MarketIndex {
+	groupID, _ := resolveGroupInfo(msg)
+	return groupMarketIndices(groupID)
+}
+
+// resolveMarketIndex 识别指数输入：常用简称、6 位数字和 指数000300（按指数代码段换算）、
+// 带市场前缀的代码或名称搜索；结果必须是指数，600519 这类个股代码一律不认
+func resolveMarketIndex(input string) (models.MarketIndex, bool) {
+	if index, ok := knownMarketIndices[input]; ok {
+		return index, true
+	}
+	code := ""
+	digits := input
+	if explicit, ok := explicitIndexDigits(strings.ToLower(input)); ok {
+		digits = explicit
+	}
+	if len(digits) == 6 && isNumeric(digits) {
+		code = indexCode(digits)
+	} else {
+		code = canonicalStockCode(input)
+		if code == "" {
+			code = lookupStock(input).Code
+		}
+	}
+	// 代码表里没有的代码只认上证 000、深证 399 指数代码段
+	if code == "" || !isMarketIndexCode(code) && (lookupSecurity(code) != nil || !isIndexCodeRange(code)) {
+		return models.MarketIndex{}, false
+	}
+	name := securityDisplayName(code)
+	if name == "" {
+		stock, err := getStockData(code)
+		if err != nil {
+			return models.MarketIndex{}, false
+		}
+		name = stock.Name
+	}
+	return models.MarketIndex{Code: code, Name: name}, true
+}
+
+// isMarketIndexCommand 只有单独的 股票指数 或 股票指数 设置/重置 才是指数设置命令，
+// 股票指数000300 这类写法交给行情查询
+func isMarketIndexCommand(content string) bool {
+	rest, ok := strings.CutPrefix(content, "股票指数")
+	if !ok {
+		return false
+	}
+	if rest == "" {
+		return true
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || strings.TrimLeft(rest, " \t\u3000") == rest {
+		return false
+	}
+	switch fields[0] {
+	case "设置", "重置", "默认":
+		return true
+	}
+	return false
+}
+
+// handleMarketIndexCommand 查看或设置本群图片表头、文字摘要和大盘行情使用的指数
+func handleMarketIndexCommand(msg *openwechat.Message, args string) {
+	groupID, groupName := resolveGroupInfo(msg)
+	if groupID == "" {
+		msg.ReplyText("只支持在群聊中设置指数")
+		return
+	}
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		msg.ReplyText(fmt.Sprintf("当前指数：%s\n设置：股票指数 设置 沪深300 科创50 恒生指数\n恢复默认：股票指数 重置",
+			formatMarketIndexNames(groupMarketIndices(groupID))))
+		return
+	}
+	switch fields[0] {
+	case "重置", "默认":
+		if err := setGroupMarketIndices(groupID, groupName, nil); err != nil {
+			msg.ReplyText(fmt.Sprintf("设置失败：%v", err))
+			return
+		}
+		msg.ReplyText(fmt.Sprintf("已恢复默认指数：%s", formatMarketIndexNames(defaultMarketIndices)))
+	case "设置":
+		if len(fields) < 2 {
+			msg.ReplyText("用法：股票指数 设置 沪深300 科创50 恒生指数")
+			return
+		}
+		if len(fields)-1 > maxGroupIndices {
+			msg.ReplyText(fmt.Sprintf("最多设置 %d 个指数", maxGroupIndices))
+			return
+		}
+		var indices []models.MarketIndex
+		var unresolved []string
+		seen := make(map[string]bool)
+		for _, field := range fields[1:] {
+			index, ok := resolveMarketIndex(field)
+			if !ok {
+				unresolved = append(unresolved, field)
+				continue
+			}
+			if seen[index.Code] {
+				continue
+			}
+			seen[index.Code] = true
+			indices = append(indices, index)
+		}
+		if len(unresolved) > 0 {
+			msg.ReplyText(fmt.Sprintf("未识别的指数：%s，未做修改\n支持的指数：%s，或 6 位指数代码",
+				strings.Join(unresolved, " "), strings.Join(supportedMarketIndexNames(), " ")))
+			return
+		}
+		if err := setGroupMarketIndices(groupID, groupName, indices); err != nil {
+			msg.ReplyText(fmt.Sprintf("设置失败：%v", err))
+			return
+		}
+		msg.ReplyText(fmt.Sprintf("已设置指数：%s", formatMarketIndexNames(indices)))
+	default:
+		msg.ReplyText("用法：股票指数 / 股票指数 设置 沪深300 科创50 恒生指数 / 股票指数 重置")
+	}
+}
+
+func setGroupMarketIndices(groupID, groupName string, indices []models.MarketIndex) error {
+	watchlistMu.Lock()
+	defer watchlistMu.Unlock()
+	store, err := loadWatchlistStore()
+	if err != nil {
+		return err
+	}
+	group := ensureGroupWatchlist(store, groupID, groupName)
+	if len(indices) == 0 {
+		indices = defaultMarketIndices
+	}
+	group.Indices = append([]models.MarketIndex(nil), indices...)
+	group.UpdatedAt = time.Now().Format(time.RFC3339)
+	return saveWatchlistStore(store)
+}
+
+// supportedMarketIndexNames 常用指数的名称，同一指数只列一次，按代码排序
+func supportedMarketIndexNames() []string {
+	names := make(map[string]string)
+	for _, index := range knownMarketIndices {
+		names[index.Code] = index.Name
+	}
+	codes := make([]string, 0, len(names))
+	for code := range names {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	out := make([]string, 0, len(codes))
+	for _, code := range codes {
+		out = append(out, names[code])
+	}
+	return out
+}
+
+func formatMarketIndexNames(indices []models.MarketIndex) string {
+	parts := make([]string, 0, len(indices))
+	for _, index := range indices {
+		parts = append(parts, fmt.Sprintf("%s(%s)", index.Name, index.Code))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,15 @@
+package services
+
+import "testing"
+
+func TestResolveMarketIndexRejectsStockDigits(t *testing.T) {
+	for _, input := range []string{"600519", "300750", "指数600519", "sh600519"} {
+		if index, ok := resolveMarketIndex(input); ok {
+			t.Errorf("resolveMarketIndex(%q) = %+v, want rejected", input, index)
+		}
+	}
+	index, ok := resolveMarketIndex("沪深300")
+	if !ok || index.Code != "sh000300" {
+		t.Errorf("resolveMarketIndex(沪深300) = %+v, %v", index, ok)
+	}
+}
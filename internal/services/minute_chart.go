@@ -0,0 +1,244 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/eatmoreapple/openwechat"
+	"github.com/luckfunc/golangBot/internal/models"
+	"html/template"
+	"math"
+	"strings"
+	"time"
+)
+
+// sessionRange 一段连续交易时间，单位为当日分钟数
+type sessionRange struct {
+	Start int
+	End   int
+}
+
+// marketSessions 分时图横轴使用的交易时段，美股为美东时间
+func marketSessions(code string) []sessionRange {
+	switch stockMarket(code) {
+	case marketHK:
+		return []sessionRange{{Start: 9*60 + 30, End: 12 * 60}, {Start: 13 * 60, End: 16 * 60}}
+	case marketUS:
+		return []sessionRange{{Start: 9*60 + 30, End: 16 * 60}}
+	}
+	return []sessionRange{{Start: 9*60 + 30, End: 11*60 + 30}, {Start: 13 * 60, End: 15 * 60}}
+}
+
+// sessionSlot 把 HHMM 换算为从开盘起的第几分钟，午休时间不占横轴
+func sessionSlot(sessions []sessionRange, hhmm string) int {
+	minute := parseHHMM(hhmm)
+	offset := 0
+	for _, session := range sessions {
+		if minute <= session.End {
+			if minute > session.Start {
+				offset += minute - session.Start
+			}
+			return offset
+		}
+		offset += session.End - session.Start
+	}
+	return offset
+}
+
+func sessionLength(sessions []sessionRange) int {
+	total := 0
+	for _, session := range sessions {
+		total += session.End - session.Start
+	}
+	return total
+}
+
+func parseHHMM(hhmm string) int {
+	hhmm = strings.ReplaceAll(hhmm, ":", "")
+	if len(hhmm) < 4 || !isNumeric(hhmm[:4]) {
+		return 0
+	}
+	return int(parseIntField(hhmm[:2]))*60 + int(parseIntField(hhmm[2:4]))
+}
+
+func formatMinuteOfDay(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
+
+func handleMinuteChart(msg *openwechat.Message, args string) {
+	lookup := extractStockLookup(args)
+	if len(lookup.Candidates) > 0 {
+		msg.ReplyText(formatCandidatesReply(lookup, "股票分时"))
+		return
+	}
+	if lookup.Code == "" {
+		for _, field := range strings.Fields(args) {
+			if suggestions := suggestSecurities(field); len(suggestions) > 0 {
+				msg.ReplyText(formatUnresolvedReply(field, suggestions))
+				return
+			}
+		}
+		msg.ReplyText("用法：股票分时 600519 / 股票分时 茅台 / 股票分时 hk00700")
+		return
+	}
+	series, err := minuteProvider.Minutes(lookup.Code)
+	if err != nil {
+		// 接口失败时改用本地记录的行情，只有关注中的股票才有记录
+		local, localErr := recordedMinuteSeries(lookup.Code, time.Now())
+		if localErr != nil {
+			msg.ReplyText(fmt.Sprintf("获取分时数据失败：%v", err))
+			return
+		}
+		series = local
+	}
+	if series.Name == "" {
+		series.Name = securityDisplayName(series.Code)
+	}
+	image, err := renderMinuteChartHTMLImage(series)
+	if err == nil {
+		_, _ = msg.ReplyImage(bytes.NewReader(image))
+		return
+	}
+	msg.ReplyText(fmt.Sprintf("生成图片失败：%v\n%s", err, formatMinuteSummary(series)))
+}
+
+// formatMinuteSummary 分时图生成失败时的文字摘要
+func formatMinuteSummary(series *models.MinuteSeries) string {
+	first := series.Points[0]
+	last := series.Points[len(series.Points)-1]
+	high, low := first.Price, first.Price
+	for _, point := range series.Points {
+		high = math.Max(high, point.Price)
+		low = math.Min(low, point.Price)
+	}
+	return fmt.Sprintf("%s (%s) %s 分时\n最新：%.2f（%s）\n最高：%.2f  最低：%.2f\n均价：%.2f  昨收：%.2f",
+		series.Name, series.Code, series.Date,
+		last.Price, formatMinuteOfDay(parseHHMM(last.Time)),
+		high, low,
+		last.AvgPrice, series.PrevClose)
+}
+
+func renderMinuteChartHTMLImage(series *models.MinuteSeries) ([]byte, error) {
+	last := series.Points[len(series.Points)-1]
+	change := last.Price - series.PrevClose
+	pct := 0.0
+	if series.PrevClose != 0 {
+		pct = change / series.PrevClose * 100
+	}
+	// 指数的成交均价没有意义，不画均价线
+	showAvg := !isMarketIndexCode(series.Code)
+	legend := []chartLegendView{{Label: "━ 价格", Class: "price-key"}}
+	if showAvg {
+		legend = append(legend, chartLegendView{Label: "━ 均价", Class: "avg-key"})
+	}
+	legend = append(legend, chartLegendView{Label: "┅ 昨收"})
+	return renderChartHTMLImage(chartPageView{
+		Name:      series.Name,
+		Code:      series.Code,
+		Title:     "分时",
+		Price:     fmt.Sprintf("%.2f", last.Price),
+		Delta:     fmt.Sprintf("%+.2f  %+.2f%%", change, pct),
+		Class:     trendClass(change),
+		Legend:    legend,
+		Chart:     template.HTML(buildMinuteChartSVG(series, showAvg)),
+		Timestamp: series.Date + " " + formatMinuteOfDay(parseHHMM(last.Time)),
+	})
+}
+
+// buildMinuteChartSVG 生成分时图：上方为价格线、均价线和昨收基准线，纵轴以昨收为中心对称，
+// 左侧标价格、右侧标涨跌幅；下方为每分钟成交量柱
+func buildMinuteChartSVG(series *models.MinuteSeries, showAvg bool) string {
+	sessions := marketSessions(series.Code)
+	total := float64(sessionLength(sessions))
+	plotWidth := float64(chartWidth - chartPaddingLeft - chartPaddingRight)
+	prevClose := series.PrevClose
+
+	spread := prevClose * 0.002
+	var maxVolume int64
+	for _, point := range series.Points {
+		spread = math.Max(spread, math.Abs(point.Price-prevClose))
+		if showAvg {
+			spread = math.Max(spread, math.Abs(point.AvgPrice-prevClose))
+		}
+		if point.Volume > maxVolume {
+			maxVolume = point.Volume
+		}
+	}
+	// 昨收为 0 且价格不变时 spread 为 0，纵坐标会除以 0，保留至少 0.01 的价格区间
+	spread = math.Max(spread*1.05, 0.01)
+	x := func(slot int) float64 {
+		return chartPaddingLeft + float64(slot)/total*plotWidth
+	}
+	y := func(price float64) float64 {
+		return chartPaddingTop + (prevClose+spread-price)/(2*spread)*chartPriceHeight
+	}
+	volumeTop := float64(chartPaddingTop + chartPriceHeight + chartGap)
+	height := chartPaddingTop + chartPriceHeight + chartGap + chartVolumeHeight + 4
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-size="13">`, chartWidth, height)
+
+	// 价格网格：上下各两档，中线为昨收
+	for i := 0; i <= 4; i++ {
+		price := prevClose + spread - float64(i)*spread/2
+		lineY := y(price)
+		class := "grid"
+		if i == 2 {
+			class = "base"
+		}
+		fmt.Fprintf(&svg, `<line class="%s" x1="%d" y1="%.1f" x2="%.1f" y2="%.1f"/>`, class, chartPaddingLeft, lineY, chartPaddingLeft+plotWidth, lineY)
+		labelClass := trendClass(price - prevClose)
+		fmt.Fprintf(&svg, `<text class="%s" x="%d" y="%.1f" text-anchor="end">%.2f</text>`, labelClass, chartPaddingLeft-8, lineY+4, price)
+		pct := 0.0
+		if prevClose != 0 {
+			pct = (price - prevClose) / prevClose * 100
+		}
+		fmt.Fprintf(&svg, `<text class="%s" x="%.1f" y="%.1f">%+.2f%%</text>`, labelClass, chartPaddingLeft+plotWidth+8, lineY+4, pct)
+	}
+
+	// 横轴时间：开盘、各时段衔接处、收盘
+	offset := 0
+	for i, session := range sessions {
+		label := formatMinuteOfDay(session.Start)
+		if i > 0 {
+			label = formatMinuteOfDay(sessions[i-1].End) + "/" + label
+		}
+		anchor := "middle"
+		if i == 0 {
+			anchor = "start"
+		}
+		fmt.Fprintf(&svg, `<line class="grid" x1="%.1f" y1="%d" x2="%.1f" y2="%.1f"/>`, x(offset), chartPaddingTop, x(offset), volumeTop+chartVolumeHeight)
+		fmt.Fprintf(&svg, `<text class="axis" x="%.1f" y="%.1f" text-anchor="%s">%s</text>`, x(offset), volumeTop-12, anchor, label)
+		offset += session.End - session.Start
+	}
+	fmt.Fprintf(&svg, `<line class="grid" x1="%.1f" y1="%d" x2="%.1f" y2="%.1f"/>`, x(offset), chartPaddingTop, x(offset), volumeTop+chartVolumeHeight)
+	fmt.Fprintf(&svg, `<text class="axis" x="%.1f" y="%.1f" text-anchor="end">%s</text>`, x(offset), volumeTop-12, formatMinuteOfDay(sessions[len(sessions)-1].End))
+
+	// 成交量柱，颜色按与上一分钟相比的涨跌
+	barWidth := math.Max(plotWidth/total*0.7, 1)
+	prevPrice := prevClose
+	for _, point := range series.Points {
+		if maxVolume > 0 && point.Volume > 0 {
+			barHeight := float64(point.Volume) / float64(maxVolume) * chartVolumeHeight
+			fmt.Fprintf(&svg, `<rect class="bar-%s" x="%.1f" y="%.1f" width="%.1f" height="%.1f"/>`,
+				trendClass(point.Price-prevPrice), x(sessionSlot(sessions, point.Time))-barWidth/2, volumeTop+chartVolumeHeight-barHeight, barWidth, barHeight)
+		}
+		prevPrice = point.Price
+	}
+	fmt.Fprintf(&svg, `<line class="grid" x1="%d" y1="%.1f" x2="%.1f" y2="%.1f"/>`, chartPaddingLeft, volumeTop+chartVolumeHeight, chartPaddingLeft+plotWidth, volumeTop+chartVolumeHeight)
+	fmt.Fprintf(&svg, `<text class="axis" x="%d" y="%.1f" text-anchor="end">%s</text>`, chartPaddingLeft-8, volumeTop+12, formatVolumeUnits(series.Code, maxVolume))
+
+	svg.WriteString(`<polyline class="price" points="`)
+	for _, point := range series.Points {
+		fmt.Fprintf(&svg, "%.1f,%.1f ", x(sessionSlot(sessions, point.Time)), y(point.Price))
+	}
+	svg.WriteString(`"/>`)
+	if showAvg {
+		svg.WriteString(`<polyline class="avg" points="`)
+		for _, point := range series.Points {
+			fmt.Fprintf(&svg, "%.1f,%.1f ", x(sessionSlot(sessions, point.Time)), y(point.AvgPrice))
+		}
+		svg.WriteString(`"/>`)
+	}
+	svg.WriteString(`</svg>`)
+	return svg.String()
+}
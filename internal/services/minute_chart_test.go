@@ -0,0 +1,18 @@
+package services
+
+import (
+	"github.com/luckfunc/golangBot/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestBuildMinuteChartSVGFlatPrices(t *testing.T) {
+	series := &models.MinuteSeries{Code: "sh600519", Points: []models.MinutePoint{
+		{Time: "0930", Price: 0, AvgPrice: 0},
+		{Time: "0931", Price: 0, AvgPrice: 0},
+	}}
+	svg := buildMinuteChartSVG(series, true)
+	if strings.Contains(svg, "NaN") || strings.Contains(svg, "Inf") {
+		t.Fatalf("svg contains invalid coordinates: %s", svg)
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/luckfunc/golangBot/internal/models"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultTencentMinuteBaseURL = "https://web.ifzq.gtimg.cn"
+
+// MinuteProvider 获取当日分时数据
+type MinuteProvider interface {
+	Minutes(code string) (*models.MinuteSeries, error)
+}
+
+var minuteProvider MinuteProvider = NewTencentMinuteProvider(envString("STOCK_MINUTE_BASE_URL", defaultTencentMinuteBaseURL))
+
+// TencentMinuteProvider 腾讯财经分时接口 web.ifzq.gtimg.cn/appstock/app/minute/query
+type TencentMinuteProvider struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewTencentMinuteProvider 创建腾讯分时数据源，baseURL 为空时使用 web.ifzq.gtimg.cn
+func NewTencentMinuteProvider(baseURL string) *TencentMinuteProvider {
+	if baseURL == "" {
+		baseURL = defaultTencentMinuteBaseURL
+	}
+	return &TencentMinuteProvider{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *TencentMinuteProvider) Minutes(code string) (*models.MinuteSeries, error) {
+	symbol := tencentSymbol(code)
+	req, err := http.NewRequest("GET", p.BaseURL+"/appstock/app/minute/query?code="+url.QueryEscape(symbol), nil)
+	if err != nil {
+		return nil, err
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tencent minute status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseTencentMinutes(body, code, symbol)
+}
+
+// parseTencentMinutes 解析分时响应：data.<代码>.data.data 每项为 "HHMM 价格 累计成交量 累计成交额"，
+// A 股成交量单位为手；data.<代码>.qt.<代码> 为实时行情字段，用于取名称和昨收
+func parseTencentMinutes(body []byte, code, symbol string) (*models.MinuteSeries, error) {
+	var payload struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+		Data map[string]struct {
+			Data struct {
+				Data []string `json:"data"`
+				Date string   `json:"date"`
+			} `json:"data"`
+			QT map[string]json.RawMessage `json:"qt"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	if payload.Code != 0 {
+		return nil, fmt.Errorf("tencent minute error: %s", payload.Msg)
+	}
+	entry, ok := payload.Data[symbol]
+	if !ok || len(entry.Data.Data) == 0 {
+		return nil, fmt.Errorf("no minute data for %s", code)
+	}
+
+	series := &models.MinuteSeries{Code: code, Date: entry.Data.Date}
+	if len(series.Date) == 8 {
+		series.Date = series.Date[:4] + "-" + series.Date[4:6] + "-" + series.Date[6:]
+	}
+	var quote []string
+	if raw, ok := entry.QT[symbol]; ok && json.Unmarshal(raw, &quote) == nil && len(quote) > 4 {
+		series.Name = quote[1]
+		series.PrevClose = parseFloatField(quote[4])
+	}
+
+	volumeUnit := int64(1)
+	if isAShareMarket(stockMarket(code)) {
+		volumeUnit = 100
+	}
+	var lastVolume int64
+	for _, item := range entry.Data.Data {
+		fields := strings.Fields(item)
+		if len(fields) < 3 {
+			continue
+		}
+		price := parseFloatField(fields[1])
+		totalVolume := parseIntField(fields[2]) * volumeUnit
+		point := models.MinutePoint{Time: fields[0], Price: price, AvgPrice: price}
+		if totalVolume > lastVolume {
+			point.Volume = totalVolume - lastVolume
+		}
+		if len(fields) > 3 && totalVolume > 0 {
+			point.AvgPrice = parseFloatField(fields[3]) / float64(totalVolume)
+		}
+		lastVolume = totalVolume
+		series.Points = append(series.Points, point)
+	}
+	if len(series.Points) == 0 {
+		return nil, fmt.Errorf("no minute data for %s", code)
+	}
+	if series.PrevClose == 0 {
+		series.PrevClose = series.Points[0].Price
+	}
+	return series, nil
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/eatmoreapple/openwechat"
+	"github.com/luckfunc/golangBot/internal/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 周报、月报在收盘推送之后发出
+const periodReportTime = "15:10"
+
+// 计算区间涨幅时请求的日 K 数量，覆盖一个月加上节假日
+const periodReportBars = 35
+
+const (
+	reportWeekly  = "weekly"
+	reportMonthly = "monthly"
+)
+
+var reportPeriodNames = map[string]string{
+	reportWeekly:  "周报",
+	reportMonthly: "月报",
+}
+
+// periodStart 区间的第一天：周报为本周一，月报为本月一日
+func periodStart(kind string, day time.Time) time.Time {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	if kind == reportMonthly {
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// isPeriodEnd 判断是否为本周或本月最后一个交易日，周五、月末休市时提前到节前最后一个交易日
+func isPeriodEnd(kind string, day time.Time) bool {
+	if !calendar().IsTradingDay(day) {
+		return false
+	}
+	next := calendar().NextTradingDay(day)
+	return !periodStart(kind, next).Equal(periodStart(kind, day))
+}
+
+// nextPeriodReport 下一次周报或月报的发送时间
+func nextPeriodReport(kind string, after time.Time) time.Time {
+	minute := parseHHMM(periodReportTime)
+	day := time.Date(after.Year(), after.Month(), after.Day(), minute/60, minute%60, 0, 0, after.Location())
+	for i := 0; i < 62; i++ {
+		if day.After(after) && isPeriodEnd(kind, day) {
+			return day
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}
+}
+
+func periodReportJob(groupID, kind string) *ScheduledJob {
+	desc := "每周最后一个交易日 " + periodReportTime
+	if kind == reportMonthly {
+		desc = "每月最后一个交易日 " + periodReportTime
+	}
+	return &ScheduledJob{
+		ID:             fmt.Sprintf("%s%s:%s", watchlistJobPrefix, kind, groupID),
+		GroupID:        groupID,
+		Name:           reportPeriodNames[kind],
+		Spec:           cronSpecAt(periodReportTime),
+		TradingDayOnly: true,
+		Desc:           desc,
+		Run: func(now time.Time) {
+			if isPeriodEnd(kind, now) {
+				runPeriodReport(groupID, kind, now)
+			}
+		},
+		NextRun: func(after time.Time) time.Time {
+			return nextPeriodReport(kind, after)
+		},
+	}
+}
+
+func runPeriodReport(groupID, kind string, now time.Time) {
+	store, err := loadWatchlistStore()
+	if err != nil {
+		return
+	}
+	group := store.Groups[groupID]
+	if group == nil || !group.Enabled || !group.Subscribed || len(group.Stocks) == 0 {
+		return
+	}
+	key := pushSlotKey(groupID, kind)
+	if !claimPush(key, now) {
+		return
+	}
+	target, ok := pushSender.find(groupID)
+	if !ok {
+		releasePush(key, now)
+		return
+	}
+	report := buildPeriodReport(kind, group.Stocks, marketIndicesOf(group), now)
+	image, err := report.image()
+	if err == nil {
+		_, err = target.SendImage(bytes.NewReader(image))
+	}
+	if err != nil {
+		if _, err := target.SendText(report.text()); err != nil {
+			releasePush(key, now)
+			return
+		}
+	}
+}
+
+// periodReport 一个区间内关注列表和指数的涨跌，StockData 中的涨跌额、涨跌幅换成区间数据
+type periodReport struct {
+	Title   string
+	Summary []string
+	Indices []indexSnapshot
+	Stocks  []*models.StockData
+	Time    string
+}
+
+// periodReturn 用日 K 计算区间涨跌：以区间开始前最后一根收盘为基准，到最新一根收盘
+func periodReturn(code string, start time.Time) (*models.StockData, bool) {
+	bars, err := fetchKLines(code, klineDay, periodReportBars)
+	if err != nil || len(bars) == 0 {
+		return nil, false
+	}
+	from := start.Format("2006-01-02")
+	base := -1
+	for i, bar := range bars {
+		if bar.Date < from {
+			base = i
+		}
+	}
+	if base < 0 || bars[base].Close == 0 {
+		return nil, false
+	}
+	last := bars[len(bars)-1]
+	change := last.Close - bars[base].Close
+	return &models.StockData{
+		Code:      code,
+		Price:     last.Close,
+		Change:    change,
+		ChangePct: change / bars[base].Close * 100,
+		PrevClose: bars[base].Close,
+		Currency:  stockCurrency(code),
+		Status:    models.StatusNormal,
+	}, true
+}
+
+func buildPeriodReport(kind string, codes []string, indices []models.MarketIndex, now time.Time) *periodReport {
+	start := periodStart(kind, now)
+	report := &periodReport{
+		Title: fmt.Sprintf("%s（%s ~ %s）", reportPeriodNames[kind], start.Format("01-02"), now.Format("01-02")),
+		Time:  now.Format("2006-01-02 15:04"),
+	}
+	names := make(map[string]string)
+	for _, stock := range fetchStocksByCodes(codes) {
+		names[stock.Code] = stock.Name
+	}
+	var missing []string
+	for _, code := range codes {
+		stock, ok := periodReturn(code, start)
+		if !ok {
+			missing = append(missing, formatCodeWithName(code))
+			continue
+		}
+		stock.Name = names[code]
+		if stock.Name == "" {
+			stock.Name = securityDisplayName(code)
+		}
+		report.Stocks = append(report.Stocks, stock)
+	}
+	sort.SliceStable(report.Stocks, func(i, j int) bool {
+		return report.Stocks[i].ChangePct > report.Stocks[j].ChangePct
+	})
+	for _, index := range indices {
+		if stock, ok := periodReturn(index.Code, start); ok {
+			stock.Name = index.Name
+			report.Indices = append(report.Indices, indexSnapshot{Name: index.Name, Stock: stock})
+		}
+	}
+
+	if len(report.Stocks) > 0 {
+		best, worst := report.Stocks[0], report.Stocks[len(report.Stocks)-1]
+		report.Summary = append(report.Summary, fmt.Sprintf("最佳：%s %+.2f%%　最差：%s %+.2f%%",
+			best.Name, best.ChangePct, worst.Name, worst.ChangePct))
+		total := 0.0
+		for _, stock := range report.Stocks {
+			total += stock.ChangePct
+		}
+		average := total / float64(len(report.Stocks))
+		comparison := []string{fmt.Sprintf("关注平均 %+.2f%%", average)}
+		for _, index := range report.Indices {
+			beat := 0
+			for _, stock := range report.Stocks {
+				if stock.ChangePct > index.Stock.ChangePct {
+					beat++
+				}
+			}
+			comparison = append(comparison, fmt.Sprintf("相对%s %+.2f%%（跑赢 %d/%d）",
+				index.Name, average-index.Stock.ChangePct, beat, len(report.Stocks)))
+		}
+		report.Summary = append(report.Summary, strings.Join(comparison, "　"))
+	}
+	if len(missing) > 0 {
+		report.Summary = append(report.Summary, "暂无数据："+strings.Join(missing, "、"))
+	}
+	return report
+}
+
+func (r *periodReport) image() ([]byte, error) {
+	return renderWatchlistReportHTMLImage(r.Title, r.Summary, r.Indices, r.Stocks, r.Time)
+}
+
+func (r *periodReport) text() string {
+	lines := []string{r.Title}
+	if len(r.Indices) > 0 {
+		parts := make([]string, 0, len(r.Indices))
+		for _, index := range r.Indices {
+			parts = append(parts, fmt.Sprintf("%s %+.2f%%", index.Name, index.Stock.ChangePct))
+		}
+		lines = append(lines, "大盘指数："+strings.Join(parts, "  "))
+	}
+	lines = append(lines, r.Summary...)
+	lines = append(lines, formatWatchlistTable(r.Stocks), "更新时间："+r.Time)
+	return strings.Join(lines, "\n")
+}
+
+// handlePeriodReport 股票周报、股票月报：查看本周、本月截至目前的表现
+func handlePeriodReport(msg *openwechat.Message, kind string) {
+	groupID, _ := resolveGroupInfo(msg)
+	if groupID == "" {
+		msg.ReplyText("只支持在群聊中查看" + reportPeriodNames[kind])
+		return
+	}
+	store, err := loadWatchlistStore()
+	if err != nil {
+		msg.ReplyText(fmt.Sprintf("读取列表失败：%v", err))
+		return
+	}
+	group := store.Groups[groupID]
+	if group == nil || len(group.Stocks) == 0 {
+		msg.ReplyText("当前没有关注股票，可用：股票添加 600519")
+		return
+	}
+	report := buildPeriodReport(kind, group.Stocks, marketIndicesOf(group), time.Now())
+	image, err := report.image()
+	if err == nil {
+		_, _ = msg.ReplyImage(bytes.NewReader(image))
+		return
+	}
+	msg.ReplyText(fmt.Sprintf("生成图片失败：%v\n%s", err, report.text()))
+}
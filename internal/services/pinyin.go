@@ -0,0 +1,111 @@
+package services
+
+import (
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"strings"
+	"unicode"
+)
+
+// GB2312 一级汉字按拼音排序，每个声母对应一段连续的区位码，下表为各段起始值
+var pinyinInitialRanges = []struct {
+	start   int
+	initial byte
+}{
+	{45217, 'a'}, {45253, 'b'}, {45761, 'c'}, {46318, 'd'}, {46826, 'e'},
+	{47010, 'f'}, {47297, 'g'}, {47614, 'h'}, {48119, 'j'}, {49062, 'k'},
+	{49324, 'l'}, {49896, 'm'}, {50371, 'n'}, {50614, 'o'}, {50622, 'p'},
+	{50906, 'q'}, {51387, 'r'}, {51446, 's'}, {52218, 't'}, {52698, 'w'},
+	{52980, 'x'}, {53689, 'y'}, {54481, 'z'},
+}
+
+// 一级汉字区位码结束值，二级汉字按部首排序无法推出拼音，改为逐字查表
+const pinyinInitialEnd = 55290
+
+// GB2312 二级汉字（0xD8A1-0xF7FE）的拼音首字母，每行对应一个区的 94 个字，多音字取常用读音
+const pinyinLevel2Start = 0xD8A1
+
+var pinyinLevel2Initials = "" +
+	"cjwgnspgcgnegypbtyyzdxykygtzjnmjqmbsgzscyjsyyfpgkbzgydywjkgkljswkpjqhyjwrdzlsgmrypywwcckznkyyg" +
+	"ttngjeykkzytcjnmcylqlypyqfqrpzslwbtgkjfyxjwzltbncxjjjjtxdttsqzycdxxhgckbphffsstybgmxlpbyllbhlx" +
+	"smzmyjhsojnghdzqyklgjhsgqzhxqgkezzwyscscjxyeyxadzpmdssmzjzqjyzcjjfwqjbdzbxgznzcpwhkxhqkmwfbpby" +
+	"dtjzzkqhylygxfptyjyyzpszlfchmqshgmxxsxjyqdcsbbqbefsjyhwwgzkpylqbgldlcctnmayddkssngycsgxlyzaypn" +
+	"ptsdkdylhgymylcxpycjndqjwqqxfyyfjlejpzrxccqwqqsbzkymgplbmjrqcflnymyqmsqtrbcjthztqfrxqhxmjjcjlx" +
+	"xgjmshzkbswyemyltxfsydsglycjqxsjnqbsctyhbftdcyjdjwyghqfrxwckqkxebptlpxjzsrmebwhjlbjslyysmdxlcl" +
+	"qkxlhxjrzjmfqhxhwywsbhtrxxglhqhfnmgykldyxzpylggsmtcfpajjzyljtyanjgbjplqgdzyqyaxbkysecjsznslyzh" +
+	"zxlzcghpxzhznytdsbcjkdlzyyfwydlebbgqyzkggldndnyskjshdlyxbcghxypkdjmmzngmmclgwzszxzjfznmlzzthcs" +
+	"ydbdllscddnlkjykjsycjlkohqasdknhcsganhdaashtcplcpqybsdmpjlpcjoqlcdhjjysprchnwjnlhlyyqyhwzptczg" +
+	"wwmzffjqqqqyxaclbhkdjxdgmmydjxzllsygxgkjrywzwyclzmssjzldbydcpcxyhlxchyzjqsqqagmnyxpfrkssbjlyxy" +
+	"syglnscmhcwwmnzjjlxxhchsyzsttxrycyxbyhcsmxjsznpwgpxxtaybgajcxlyxdccwzocwkccsbnhcpdyznfcyytyckx" +
+	"kybsqkkytqqxfcwchcykelzqbsqyjqcclmthsywhmktlkjlycxwheqqhtqhqpqsqscfymmdmgbwhwlgsllystlmlxpthmj" +
+	"hwljzyhzjxhtxjlhxrswlwzjcbxmhzqxsdzpmgfcsglsxymjshxpjxwmyqksmyplrthbxftpmhyxlchlhlzylxgsssstcl" +
+	"sldclrpbhzhxyyfhbmgdmycnqqwlqhjjcywjzyejjdhpblqxtqkwhlchqxagtlxljxmsljhtzkzjecxjcjnmfbycsfywyb" +
+	"jzgnysdzsqyrsljpclpwxsdwejbjcbcnaytwgmpapclyqpclzxsbnmsggfnzjjbzsfzyndxhplqkzczwalsbccjxsyzgwk" +
+	"ypsgxfzfcdkhjgxtlqfsgdslqwzkxtmhsbgzmjzrglyjbpmlmsxlzjqqhzyjczydjwbwjklddpmjegxyhylxhlqyqhkycw" +
+	"cjmyyxnatjhyccxzpcqlbzwwytwbqcmlpmyrjcccxfpznzzljplxxyztzlgdldcklyrzzgqtgjhhgjljaxfgfjzslcfdqz" +
+	"lclgjdjzsnzlljpjqdcclcjxmyzftsxgcgsbrzxjqqctzhgyqtjqqlzxjylylbcyamcstylpdjbyregklzyzhlyszqlznw" +
+	"czcllwjqjjjkdgjzolbbzppglghtgzxyjhzmycnqcycyhbhgxkamtxyxnbskyzzgjzlqjdfcjxdygjqjjpmgwgjjjpkqsb" +
+	"gbmmcjssclpqpdxcdyykypcjddyygywrhjrtgznyqldkljszzgzqzjgdykshpzmtlcpwnjyfyzdjcnmwescyglbtzcgmss" +
+	"llyxysxsbsjsbbsgghfjlypmzjnlyywdqshzxtyywhmcyhywdbxbtlmsyyyfsxjcbdxxlhjhfssxzqhfzmzcztqcxzxrtt" +
+	"djhnnyzqqmtqdmmgyydxmjgdhcdyzbffallztdltfxmxqzdngwqdbdcdjdxbzgsqqddjcmbkzffxmkdmdsyyszcmljdsyn" +
+	"sprskmkmpcklgtbqtfzswtfgglyplljzhgjjgypzltcsmcnbtjbqfkthbyzgkpbbymtdssxtbnpdkleycjnyddykzddhqh" +
+	"sdzsctarlltkzlgecllkjlqjaqnbdkkghpjtzqksecshalqfmmgjnlyjbbtmlyzxdcjpldlpcqdhzycbzsczbzmsljflkr" +
+	"zjsnfrgjhxpdhyjybzgdlqcsezgxlblgyxtwmabchecmwyjyzlljjyhlgndjlslygkdzpzxjyyzlwcxszfgwyydlyhcljs" +
+	"cmbjhblyzlycblydpdqysxqzbytdkyxjyycnrjmpdjgklcljbctbjddbblblczqrppxjcjlzcshltoljnmdddlngkathqh" +
+	"jhykheznmshrphqqjchgmfprxhjgdychghlyrzqlcyqjnzsqtkqjymszswlcfqqqxyfggyptqwlmcrnfkkfsyylqbmqamm" +
+	"myxctpshcptxxzzsmphpshmclmldqfyqxszyjdjjzzhqpdszglstjbckbxyqzysgpsxqzqzrqtbdkyxzkhhgflbcsmdldg" +
+	"dzdblzyycxnncsybzbfglzzxswmsccmqnjqsbdqsjtxxmbltxzclzshzcxrqjgjylxzfjphymzqqydfqjjlzznzjsdgzyg" +
+	"ctxmzysctlkphtxhtlbjxjlxscdqxcbbtjfqzfsltjbtkqbxxjjljchczdbzjdczjdcprnpqcjpfczlclzxzdmxmphjsgz" +
+	"gszzqjylwtjpfsyaxmcjbtzkycwmytzsjjlqcqlwzmalbxyfbpnlsfhtgjwejjxxglljstgshjqlzfkcgnndszfdeqfhbs" +
+	"aqtgylbxmmygszldydqmjjrgbjtkgdhgkblqkbdmbylxwcxyttybkmrtjzxqjbhlmhmjjzmqasldcyxyqdlqcafywyxqhz"
+
+// pinyinInitials 返回名称的拼音首字母，例如 贵州茅台 -> gzmt；字母和数字原样保留（小写），
+// 无法识别的汉字和符号会被跳过
+func pinyinInitials(name string) string {
+	encoder := simplifiedchinese.GBK.NewEncoder()
+	var builder strings.Builder
+	for _, r := range name {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			builder.WriteRune(unicode.ToLower(r))
+		case unicode.Is(unicode.Han, r):
+			encoded, err := encoder.String(string(r))
+			if err != nil || len(encoded) != 2 {
+				continue
+			}
+			if initial := gbkInitial(int(encoded[0])<<8 | int(encoded[1])); initial != 0 {
+				builder.WriteByte(initial)
+			}
+		}
+	}
+	return builder.String()
+}
+
+func gbkInitial(code int) byte {
+	if code >= pinyinInitialEnd {
+		return gbkLevel2Initial(code)
+	}
+	if code < pinyinInitialRanges[0].start {
+		return 0
+	}
+	initial := pinyinInitialRanges[0].initial
+	for _, item := range pinyinInitialRanges {
+		if code < item.start {
+			break
+		}
+		initial = item.initial
+	}
+	return initial
+}
+
+// gbkLevel2Initial 按区位在二级汉字表中查首字母
+func gbkLevel2Initial(code int) byte {
+	row, cell := code>>8-pinyinLevel2Start>>8, code&0xFF-0xA1
+	if row < 0 || cell < 0 || cell >= 94 {
+		return 0
+	}
+	index := row*94 + cell
+	if index >= len(pinyinLevel2Initials) {
+		return 0
+	}
+	return pinyinLevel2Initials[index]
+}
@@ -0,0 +1,24 @@
+package services
+
+import "testing"
+
+func TestPinyinInitials(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"贵州茅台", "gzmt"},
+		{"濮耐股份", "pngf"},
+		{"亘通股份", "gtgf"},
+		{"*ST邯钢", "sthg"},
+		{"万科A", "wka"},
+		{"赣锋锂业", "gfly"},
+		{"隆基绿能", "ljln"},
+		{"璞泰来", "ptl"},
+	}
+	for _, tt := range tests {
+		if got := pinyinInitials(tt.name); got != tt.want {
+			t.Errorf("pinyinInitials(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"fmt"
+	"github.com/eatmoreapple/openwechat"
+	"github.com/luckfunc/golangBot/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 每个群最多设置的推送时间
+const maxPushSlots = 6
+
+// 新群默认只在收盘后推送一次
+var defaultPushSlots = []models.PushSlot{
+	{Time: dailyPushTime, Name: "每日收盘"},
+}
+
+// groupPushSlots 群的推送时间，未配置的群使用默认值
+func groupPushSlots(groupID string) []models.PushSlot {
+	store, err := loadWatchlistStore()
+	if err != nil {
+		return defaultPushSlots
+	}
+	return pushSlotsOf(store.Groups[groupID])
+}
+
+func pushSlotsOf(group *models.GroupWatchlist) []models.PushSlot {
+	if group == nil || group.PushSlots == nil {
+		return defaultPushSlots
+	}
+	return group.PushSlots
+}
+
+// findPushSlot 按时间查找群的推送时间
+func findPushSlot(group *models.GroupWatchlist, hhmm string) (models.PushSlot, bool) {
+	for _, slot := range pushSlotsOf(group) {
+		if slot.Time == hhmm {
+			return slot, true
+		}
+	}
+	return models.PushSlot{}, false
+}
+
+// parsePushTime 接受 9:26、09:26、0926 三种写法，统一为 09:26
+func parsePushTime(text string) (string, bool) {
+	hourText, minuteText, ok := strings.Cut(strings.ReplaceAll(text, "：", ":"), ":")
+	if !ok {
+		if len(text) != 4 {
+			return "", false
+		}
+		hourText, minuteText = text[:2], text[2:]
+	}
+	hour, err := strconv.Atoi(hourText)
+	if err != nil || hour < 0 || hour > 23 {
+		return "", false
+	}
+	minute, err := strconv.Atoi(minuteText)
+	if err != nil || minute < 0 || minute > 59 || len(minuteText) != 2 {
+		return "", false
+	}
+	return formatMinuteOfDay(hour*60 + minute), true
+}
+
+func formatPushSlots(slots []models.PushSlot) string {
+	if len(slots) == 0 {
+		return "无"
+	}
+	parts := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		parts = append(parts, slot.Time+" "+slot.Name)
+	}
+	return strings.Join(parts, "、")
+}
+
+// addGroupPushSlot 添加推送时间，同一时间已存在时只更新名称
+func addGroupPushSlot(groupID, groupName string, slot models.PushSlot) error {
+	watchlistMu.Lock()
+	defer watchlistMu.Unlock()
+	store, err := loadWatchlistStore()
+	if err != nil {
+		return err
+	}
+	group := ensureGroupWatchlist(store, groupID, groupName)
+	slots := append([]models.PushSlot(nil), pushSlotsOf(group)...)
+	replaced := false
+	for i := range slots {
+		if slots[i].Time == slot.Time {
+			slots[i].Name = slot.Name
+			replaced = true
+		}
+	}
+	now := time.Now()
+	if !replaced {
+		if len(slots) >= maxPushSlots {
+			return fmt.Errorf("最多设置 %d 个推送时间", maxPushSlots)
+		}
+		slots = append(slots, slot)
+		// 今天已过的时间从明天开始推送；保存后补发任务立即生效，所以先记为已推送
+		if now.After(pushSlotAt(slot.Time, now)) {
+			markPushed(pushSlotKey(groupID, slot.Time), now)
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Time < slots[j].Time })
+	group.PushSlots = slots
+	group.UpdatedAt = now.Format(time.RFC3339)
+	return saveWatchlistStore(store)
+}
+
+// removeGroupPushSlot 删除推送时间，返回是否找到
+func removeGroupPushSlot(groupID, groupName, hhmm string) (bool, error) {
+	watchlistMu.Lock()
+	defer watchlistMu.Unlock()
+	store, err := loadWatchlistStore()
+	if err != nil {
+		return false, err
+	}
+	group := ensureGroupWatchlist(store, groupID, groupName)
+	slots := []models.PushSlot{}
+	found := false
+	for _, slot := range pushSlotsOf(group) {
+		if slot.Time == hhmm {
+			found = true
+			continue
+		}
+		slots = append(slots, slot)
+	}
+	if !found {
+		return false, nil
+	}
+	group.PushSlots = slots
+	group.UpdatedAt = time.Now().Format(time.RFC3339)
+	return true, saveWatchlistStore(store)
+}
+
+func resetGroupPushSlots(groupID, groupName string) error {
+	watchlistMu.Lock()
+	defer watchlistMu.Unlock()
+	store, err := loadWatchlistStore()
+	if err != nil {
+		return err
+	}
+	group := ensureGroupWatchlist(store, groupID, groupName)
+	group.PushSlots = append([]models.PushSlot(nil), defaultPushSlots...)
+	group.UpdatedAt = time.Now().Format(time.RFC3339)
+	return saveWatchlistStore(store)
+}
+
+// handlePushSlotCommand 股票推送时间：查看、添加、删除、重置本群的定时推送
+func handlePushSlotCommand(msg *openwechat.Message, args string) {
+	groupID, groupName := resolveGroupInfo(msg)
+	if groupID == "" {
+		msg.ReplyText("只支持在群聊中设置推送时间")
+		return
+	}
+	usage := "用法：股票推送时间 添加 11:31 午盘 / 股票推送时间 删除 11:31 / 股票推送时间 重置"
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		msg.ReplyText(fmt.Sprintf("本群推送时间：%s\n需先开启：股票订阅\n%s", formatPushSlots(groupPushSlots(groupID)), usage))
+		return
+	}
+	switch fields[0] {
+	case "添加", "设置":
+		if len(fields) < 2 {
+			msg.ReplyText(usage)
+			return
+		}
+		hhmm, ok := parsePushTime(fields[1])
+		if !ok {
+			msg.ReplyText("时间格式不正确，例如：股票推送时间 添加 09:26 竞价")
+			return
+		}
+		name := strings.Join(fields[2:], " ")
+		if name == "" {
+			name = "定时推送"
+		}
+		if err := addGroupPushSlot(groupID, groupName, models.PushSlot{Time: hhmm, Name: name}); err != nil {
+			msg.ReplyText(fmt.Sprintf("设置失败：%v", err))
+			return
+		}
+		msg.ReplyText(fmt.Sprintf("已添加推送：%s %s（交易日）\n本群推送时间：%s", hhmm, name, formatPushSlots(groupPushSlots(groupID))))
+	case "删除", "移除":
+		if len(fields) < 2 {
+			msg.ReplyText(usage)
+			return
+		}
+		hhmm, ok := parsePushTime(fields[1])
+		if !ok {
+			msg.ReplyText("时间格式不正确，例如：股票推送时间 删除 11:31")
+			return
+		}
+		found, err := removeGroupPushSlot(groupID, groupName, hhmm)
+		if err != nil {
+			msg.ReplyText(fmt.Sprintf("设置失败：%v", err))
+			return
+		}
+		if !found {
+			msg.ReplyText(fmt.Sprintf("本群没有 %s 的推送", hhmm))
+			return
+		}
+		msg.ReplyText(fmt.Sprintf("已删除 %s 的推送\n本群推送时间：%s", hhmm, formatPushSlots(groupPushSlots(groupID))))
+	case "重置", "默认":
+		if err := resetGroupPushSlots(groupID, groupName); err != nil {
+			msg.ReplyText(fmt.Sprintf("设置失败：%v", err))
+			return
+		}
+		msg.ReplyText(fmt.Sprintf("已恢复默认推送时间：%s", formatPushSlots(defaultPushSlots)))
+	default:
+		msg.ReplyText(usage)
+	}
+}
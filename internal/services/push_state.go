@@ -0,0 +1,143 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/luckfunc/golangBot/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const pushStateFileName = "push_state.json"
+
+// 超过这个时间的定时提醒记录不再影响推送，保存时清理掉
+const pushStateRetention = 7 * 24 * time.Hour
+
+var pushStateMu sync.Mutex
+var pushState *models.PushState
+
+// loadedPushState 首次使用时从文件读取推送记录，调用方需持有 pushStateMu
+func loadedPushState() *models.PushState {
+	if pushState != nil {
+		return pushState
+	}
+	state, err := loadPushState()
+	if err != nil {
+		fmt.Println("load push state:", err)
+		state = &models.PushState{Version: 1}
+	}
+	if state.DailyPushed == nil {
+		state.DailyPushed = make(map[string]string)
+	}
+	if state.IntervalPushed == nil {
+		state.IntervalPushed = make(map[string]time.Time)
+	}
+	// 早期只有收盘一次推送，记录按群 ID 保存
+	for key, date := range state.DailyPushed {
+		if !strings.Contains(key, "|") {
+			delete(state.DailyPushed, key)
+			state.DailyPushed[pushSlotKey(key, dailyPushTime)] = date
+		}
+	}
+	pushState = state
+	return pushState
+}
+
+// restorePushState 启动时读取推送记录，重启前已推送的内容不会再推一次
+func restorePushState() {
+	pushStateMu.Lock()
+	defer pushStateMu.Unlock()
+	loadedPushState()
+}
+
+func loadPushState() (*models.PushState, error) {
+	data, err := os.ReadFile(pushStateFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &models.PushState{Version: 1}, nil
+		}
+		return nil, err
+	}
+	var state models.PushState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// savePushState 写入推送记录，调用方需持有 pushStateMu
+func savePushState(state *models.PushState, now time.Time) {
+	for key, at := range state.IntervalPushed {
+		if now.Sub(at) > pushStateRetention {
+			delete(state.IntervalPushed, key)
+		}
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		fmt.Println("save push state:", err)
+		return
+	}
+	if err := os.WriteFile(pushStateFilePath(), data, 0644); err != nil {
+		fmt.Println("save push state:", err)
+	}
+}
+
+func pushStateFilePath() string {
+	return filepath.Join(filepath.Dir(watchlistFilePath()), pushStateFileName)
+}
+
+func markPushed(key string, now time.Time) {
+	pushStateMu.Lock()
+	defer pushStateMu.Unlock()
+	state := loadedPushState()
+	state.DailyPushed[key] = now.Format("2006-01-02")
+	savePushState(state, now)
+}
+
+// claimPush 当天还没推送时立即记为已推送并返回 true，检查和记录在同一把锁内完成，
+// 同一推送的定时任务和补发任务并发执行时只有一个能发出；发送失败时调用 releasePush
+func claimPush(key string, now time.Time) bool {
+	pushStateMu.Lock()
+	defer pushStateMu.Unlock()
+	state := loadedPushState()
+	today := now.Format("2006-01-02")
+	if state.DailyPushed[key] == today {
+		return false
+	}
+	state.DailyPushed[key] = today
+	savePushState(state, now)
+	return true
+}
+
+// releasePush 撤销 claimPush 的记录，留给下一次任务或补发重试
+func releasePush(key string, now time.Time) {
+	pushStateMu.Lock()
+	defer pushStateMu.Unlock()
+	state := loadedPushState()
+	if state.DailyPushed[key] != now.Format("2006-01-02") {
+		return
+	}
+	delete(state.DailyPushed, key)
+	savePushState(state, now)
+}
+
+func shouldIntervalPush(groupID, code string, minutes int, now time.Time) bool {
+	pushStateMu.Lock()
+	defer pushStateMu.Unlock()
+	last, ok := loadedPushState().IntervalPushed[groupID+"|"+code]
+	if !ok {
+		return true
+	}
+	return now.Sub(last) >= time.Duration(minutes)*time.Minute
+}
+
+func markIntervalPushed(groupID, code string, now time.Time) {
+	pushStateMu.Lock()
+	defer pushStateMu.Unlock()
+	state := loadedPushState()
+	state.IntervalPushed[groupID+"|"+code] = now
+	savePushState(state, now)
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClaimPush(t *testing.T) {
+	t.Chdir(t.TempDir())
+	pushStateMu.Lock()
+	saved := pushState
+	pushState = nil
+	pushStateMu.Unlock()
+	t.Cleanup(func() {
+		pushStateMu.Lock()
+		pushState = saved
+		pushStateMu.Unlock()
+	})
+
+	now := time.Date(2026, 10, 16, 15, 5, 0, 0, time.Local)
+	key := pushSlotKey("g1", "15:05")
+	var claimed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if claimPush(key, now) {
+				claimed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := claimed.Load(); got != 1 {
+		t.Fatalf("concurrent claims = %d, want 1", got)
+	}
+	if claimPush(key, now.Add(time.Minute)) {
+		t.Fatal("claimed again on the same day")
+	}
+	releasePush(key, now)
+	if !claimPush(key, now.Add(time.Minute)) {
+		t.Fatal("claim after release failed")
+	}
+	if !claimPush(key, now.AddDate(0, 0, 1)) {
+		t.Fatal("claim on the next day failed")
+	}
+}
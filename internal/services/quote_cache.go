@@ -0,0 +1,162 @@
+package services
+
+import (
+	"fmt"
+	"github.com/luckfunc/golangBot/internal/models"
+	"sync"
+	"time"
+)
+
+// 行情缓存时间，定时推送和群内查询在同一分钟内会复用同一份行情
+var quoteCacheTTL = envDuration("STOCK_QUOTE_CACHE_TTL", 15*time.Second)
+
+var sharedQuoteCache = newQuoteCache(quoteCacheTTL)
+
+// QuoteCacheStats 行情缓存统计
+type QuoteCacheStats struct {
+	Hits      int64 // 直接命中缓存
+	Coalesced int64 // 等待其它请求的结果
+	Misses    int64 // 需要请求数据源
+	Upstream  int64 // 实际发出的数据源请求次数
+	Entries   int   // 当前缓存的代码数量
+}
+
+type quoteCacheEntry struct {
+	stock     *models.StockData
+	fetchedAt time.Time
+}
+
+// quoteCall 表示一次进行中的请求，其它相同代码的查询会等待它完成
+type quoteCall struct {
+	done  chan struct{}
+	stock *models.StockData
+	err   error
+}
+
+type quoteCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	entries  map[string]quoteCacheEntry
+	inflight map[string]*quoteCall
+	stats    QuoteCacheStats
+}
+
+func newQuoteCache(ttl time.Duration) *quoteCache {
+	return &quoteCache{
+		ttl:      ttl,
+		entries:  make(map[string]quoteCacheEntry),
+		inflight: make(map[string]*quoteCall),
+	}
+}
+
+// get 按传入顺序返回行情：未过期的直接使用，正在请求的等待结果，其余合并为一次批量请求
+func (c *quoteCache) get(codes []string, fetch func([]string) ([]*models.StockData, error)) ([]*models.StockData, error) {
+	now := time.Now()
+	calls := make(map[string]*quoteCall, len(codes))
+	cached := make(map[string]*models.StockData)
+	var missing []string
+
+	c.mu.Lock()
+	for _, code := range codes {
+		if _, seen := calls[code]; seen {
+			continue
+		}
+		if _, seen := cached[code]; seen {
+			continue
+		}
+		if entry, ok := c.entries[code]; ok && now.Sub(entry.fetchedAt) < c.ttl {
+			c.stats.Hits++
+			cached[code] = entry.stock
+			continue
+		}
+		if call, ok := c.inflight[code]; ok {
+			c.stats.Coalesced++
+			calls[code] = call
+			continue
+		}
+		c.stats.Misses++
+		call := &quoteCall{done: make(chan struct{})}
+		c.inflight[code] = call
+		calls[code] = call
+		missing = append(missing, code)
+	}
+	if len(missing) > 0 {
+		c.stats.Upstream++
+	}
+	c.mu.Unlock()
+
+	if len(missing) > 0 {
+		stocks, err := fetch(missing)
+		byCode := make(map[string]*models.StockData, len(stocks))
+		for _, stock := range stocks {
+			byCode[stock.Code] = stock
+		}
+		fetchedAt := time.Now()
+		c.mu.Lock()
+		for _, code := range missing {
+			call := c.inflight[code]
+			delete(c.inflight, code)
+			if stock, ok := byCode[code]; ok {
+				call.stock = stock
+				c.entries[code] = quoteCacheEntry{stock: stock, fetchedAt: fetchedAt}
+			} else if err != nil {
+				call.err = err
+			} else {
+				call.err = fmt.Errorf("no quote data for %s", code)
+			}
+			close(call.done)
+		}
+		c.mu.Unlock()
+	}
+
+	var out []*models.StockData
+	var lastErr error
+	for _, code := range codes {
+		stock, ok := cached[code]
+		if !ok {
+			call, ok := calls[code]
+			if !ok {
+				continue
+			}
+			<-call.done
+			if call.err != nil {
+				lastErr = call.err
+				continue
+			}
+			stock = call.stock
+		}
+		// 返回副本，避免调用方修改缓存内容
+		clone := *stock
+		out = append(out, &clone)
+	}
+	if len(out) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return out, nil
+}
+
+func (c *quoteCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]quoteCacheEntry)
+}
+
+func (c *quoteCache) snapshot() QuoteCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	return stats
+}
+
+// GetQuoteCacheStats 返回行情缓存的命中统计
+func GetQuoteCacheStats() QuoteCacheStats {
+	return sharedQuoteCache.snapshot()
+}
+
+// fetchQuotes 经由共享缓存批量获取行情
+func fetchQuotes(codes []string) ([]*models.StockData, error) {
+	return sharedQuoteCache.get(codes, func(missing []string) ([]*models.StockData, error) {
+		return currentQuoteProvider().Quotes(missing)
+	})
+}
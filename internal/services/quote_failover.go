@@ -0,0 +1,207 @@
+package services
+
+import (
+	"fmt"
+	"github.com/luckfunc/golangBot/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 数据源连续失败达到阈值后进入冷却，冷却结束前请求直接交给下一个数据源
+var quoteFailoverThreshold = envInt("STOCK_QUOTE_FAILOVER_THRESHOLD", 2)
+var quoteFailoverCooldown = envDuration("STOCK_QUOTE_FAILOVER_COOLDOWN", 5*time.Minute)
+
+// QuoteProviderHealth 数据源健康状态
+type QuoteProviderHealth struct {
+	Name        string
+	Failures    int       // 连续失败次数
+	LastError   string    // 最近一次失败原因
+	LastSuccess time.Time // 最近一次成功时间
+	DownUntil   time.Time // 冷却截止时间，零值表示可用
+}
+
+// FailoverQuoteProvider 按顺序尝试多个数据源：主数据源失败时自动切换到备用数据源，
+// 冷却时间过后再重新尝试主数据源
+type FailoverQuoteProvider struct {
+	providers []QuoteProvider
+	threshold int
+	cooldown  time.Duration
+	mu        sync.Mutex
+	health    []QuoteProviderHealth
+}
+
+// NewFailoverQuoteProvider 创建故障切换数据源，providers 的顺序即优先级
+func NewFailoverQuoteProvider(threshold int, cooldown time.Duration, providers ...QuoteProvider) *FailoverQuoteProvider {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	health := make([]QuoteProviderHealth, len(providers))
+	for i, provider := range providers {
+		health[i].Name = provider.Name()
+	}
+	return &FailoverQuoteProvider{
+		providers: providers,
+		threshold: threshold,
+		cooldown:  cooldown,
+		health:    health,
+	}
+}
+
+func (p *FailoverQuoteProvider) Name() string {
+	names := make([]string, 0, len(p.providers))
+	for _, provider := range p.providers {
+		names = append(names, provider.Name())
+	}
+	return "failover(" + strings.Join(names, ",") + ")"
+}
+
+func (p *FailoverQuoteProvider) Quote(code string) (*models.StockData, error) {
+	var stock *models.StockData
+	err := p.try(func(provider QuoteProvider) error {
+		var err error
+		stock, err = provider.Quote(code)
+		if err == nil && stock == nil {
+			err = errEmptyQuote
+		}
+		return err
+	})
+	return stock, err
+}
+
+// errEmptyQuote 数据源返回成功但没有任何数据，新浪被限流或缺少 Referer 时会返回空内容
+var errEmptyQuote = fmt.Errorf("empty quote response")
+
+// Quotes 依次向可用的数据源请求，前一个数据源缺失的代码交给下一个数据源补查，结果按传入顺序返回。
+// 某个数据源一只都没有返回、而其它数据源查到了其中的代码时，按失败处理并计入冷却；
+// 所有数据源都没有数据时说明代码本身无效，不影响数据源的健康状态
+func (p *FailoverQuoteProvider) Quotes(codes []string) ([]*models.StockData, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	found := make(map[string]*models.StockData)
+	missing := codes
+	var errs []string
+	// 返回空结果的数据源及当时请求的代码，等其它数据源的结果出来后再判断是否算失败
+	empty := make(map[int][]string)
+	for _, i := range p.order() {
+		stocks, err := p.providers[i].Quotes(missing)
+		if err == nil && len(stocks) == 0 {
+			empty[i] = missing
+			continue
+		}
+		p.record(i, err)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", p.providers[i].Name(), err))
+			continue
+		}
+		for _, stock := range stocks {
+			found[stock.Code] = stock
+		}
+		var rest []string
+		for _, code := range missing {
+			if found[code] == nil {
+				rest = append(rest, code)
+			}
+		}
+		missing = rest
+		if len(missing) == 0 {
+			break
+		}
+	}
+	for i, asked := range empty {
+		for _, code := range asked {
+			if found[code] != nil {
+				p.record(i, errEmptyQuote)
+				errs = append(errs, fmt.Sprintf("%s: %v", p.providers[i].Name(), errEmptyQuote))
+				break
+			}
+		}
+	}
+	stocks := make([]*models.StockData, 0, len(found))
+	for _, code := range codes {
+		if stock := found[code]; stock != nil {
+			stocks = append(stocks, stock)
+		}
+	}
+	if len(stocks) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("all quote providers failed: %s", strings.Join(errs, "; "))
+	}
+	return stocks, nil
+}
+
+func (p *FailoverQuoteProvider) ResolveCode(code string) string {
+	for _, i := range p.order() {
+		if resolved := p.providers[i].ResolveCode(code); resolved != "" {
+			return resolved
+		}
+	}
+	return ""
+}
+
+// Health 返回各数据源的健康状态
+func (p *FailoverQuoteProvider) Health() []QuoteProviderHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]QuoteProviderHealth, len(p.health))
+	copy(out, p.health)
+	return out
+}
+
+// try 依次调用可用的数据源，直到有一个成功
+func (p *FailoverQuoteProvider) try(call func(QuoteProvider) error) error {
+	var errs []string
+	for _, i := range p.order() {
+		err := call(p.providers[i])
+		p.record(i, err)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", p.providers[i].Name(), err))
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("no quote provider configured")
+	}
+	return fmt.Errorf("all quote providers failed: %s", strings.Join(errs, "; "))
+}
+
+// order 返回本次请求的尝试顺序：冷却中的数据源排在最后，保证全部冷却时仍会尝试
+func (p *FailoverQuoteProvider) order() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var ready, cooling []int
+	for i := range p.providers {
+		if now.Before(p.health[i].DownUntil) {
+			cooling = append(cooling, i)
+			continue
+		}
+		ready = append(ready, i)
+	}
+	return append(ready, cooling...)
+}
+
+func (p *FailoverQuoteProvider) record(i int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	health := &p.health[i]
+	if err == nil {
+		health.Failures = 0
+		health.LastSuccess = time.Now()
+		health.DownUntil = time.Time{}
+		return
+	}
+	health.Failures++
+	health.LastError = err.Error()
+	if health.Failures >= p.threshold {
+		health.DownUntil = time.Now().Add(p.cooldown)
+	}
+}
+
+// GetQuoteProviderHealth 返回当前数据源的健康状态，非故障切换数据源返回 nil
+func GetQuoteProviderHealth() []QuoteProviderHealth {
+	if failover, ok := currentQuoteProvider().(*FailoverQuoteProvider); ok {
+		return failover.Health()
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"fmt"
+	"github.com/luckfunc/golangBot/internal/models"
+	"testing"
+	"time"
+)
+
+// fakeQuoteProvider 按代码返回固定行情，err 不为空时整批失败
+type fakeQuoteProvider struct {
+	name   string
+	quotes map[string]*models.StockData
+	err    error
+	asked  [][]string
+}
+
+func (f *fakeQuoteProvider) Name() string { return f.name }
+
+func (f *fakeQuoteProvider) Quote(code string) (*models.StockData, error) {
+	stocks, err := f.Quotes([]string{code})
+	if err != nil || len(stocks) == 0 {
+		return nil, err
+	}
+	return stocks[0], nil
+}
+
+func (f *fakeQuoteProvider) Quotes(codes []string) ([]*models.StockData, error) {
+	f.asked = append(f.asked, codes)
+	if f.err != nil {
+		return nil, f.err
+	}
+	var stocks []*models.StockData
+	for _, code := range codes {
+		if stock, ok := f.quotes[code]; ok {
+			stocks = append(stocks, stock)
+		}
+	}
+	return stocks, nil
+}
+
+func (f *fakeQuoteProvider) ResolveCode(code string) string { return "" }
+
+func fakeQuotes(codes ...string) map[string]*models.StockData {
+	quotes := make(map[string]*models.StockData)
+	for _, code := range codes {
+		quotes[code] = &models.StockData{Code: code, Price: 10}
+	}
+	return quotes
+}
+
+func quoteCodes(stocks []*models.StockData) []string {
+	codes := make([]string, 0, len(stocks))
+	for _, stock := range stocks {
+		codes = append(codes, stock.Code)
+	}
+	return codes
+}
+
+func TestFailoverQuotes(t *testing.T) {
+	tests := []struct {
+		name          string
+		primary       *fakeQuoteProvider
+		secondary     *fakeQuoteProvider
+		codes         []string
+		want          []string
+		wantErr       bool
+		primaryDown   bool
+		secondaryAsks []string
+	}{
+		{
+			name:      "primary answers everything",
+			primary:   &fakeQuoteProvider{name: "sina", quotes: fakeQuotes("sh600519", "sz000001")},
+			secondary: &fakeQuoteProvider{name: "tencent", quotes: fakeQuotes("sh600519", "sz000001")},
+			codes:     []string{"sh600519", "sz000001"},
+			want:      []string{"sh600519", "sz000001"},
+		},
+		{
+			name:          "empty primary body fails over",
+			primary:       &fakeQuoteProvider{name: "sina", quotes: fakeQuotes()},
+			secondary:     &fakeQuoteProvider{name: "tencent", quotes: fakeQuotes("sh600519")},
+			codes:         []string{"sh600519"},
+			want:          []string{"sh600519"},
+			primaryDown:   true,
+			secondaryAsks: []string{"sh600519"},
+		},
+		{
+			name:          "missing codes are re-queried in order",
+			primary:       &fakeQuoteProvider{name: "sina", quotes: fakeQuotes("sz000001")},
+			secondary:     &fakeQuoteProvider{name: "tencent", quotes: fakeQuotes("sh600519", "hk00700")},
+			codes:         []string{"sh600519", "sz000001", "hk00700"},
+			want:          []string{"sh600519", "sz000001", "hk00700"},
+			secondaryAsks: []string{"sh600519", "hk00700"},
+		},
+		{
+			name:          "unknown code keeps providers healthy",
+			primary:       &fakeQuoteProvider{name: "sina", quotes: fakeQuotes()},
+			secondary:     &fakeQuoteProvider{name: "tencent", quotes: fakeQuotes()},
+			codes:         []string{"sh688888"},
+			want:          []string{},
+			secondaryAsks: []string{"sh688888"},
+		},
+		{
+			name:      "all providers fail",
+			primary:   &fakeQuoteProvider{name: "sina", err: fmt.Errorf("timeout")},
+			secondary: &fakeQuoteProvider{name: "tencent", err: fmt.Errorf("timeout")},
+			codes:     []string{"sh600519"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewFailoverQuoteProvider(1, time.Minute, tt.primary, tt.secondary)
+			stocks, err := provider.Quotes(tt.codes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := fmt.Sprint(quoteCodes(stocks)); got != fmt.Sprint(tt.want) {
+				t.Errorf("codes = %s, want %s", got, fmt.Sprint(tt.want))
+			}
+			down := !provider.Health()[0].DownUntil.IsZero()
+			if down != tt.primaryDown {
+				t.Errorf("primary down = %v, want %v", down, tt.primaryDown)
+			}
+			var asked []string
+			if len(tt.secondary.asked) > 0 {
+				asked = tt.secondary.asked[0]
+			}
+			if fmt.Sprint(asked) != fmt.Sprint(tt.secondaryAsks) {
+				t.Errorf("secondary asked %v, want %v", asked, tt.secondaryAsks)
+			}
+		})
+	}
+}
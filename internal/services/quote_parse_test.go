@@ -0,0 +1,148 @@
+package services
+
+import (
+	"github.com/luckfunc/golangBot/internal/models"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// loadQuoteFixture 读取 testdata 中录制的 GBK 响应并转为 UTF-8，与 fetch 的处理一致
+func loadQuoteFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := simplifiedchinese.GBK.NewDecoder().Bytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func assertStock(t *testing.T, got, want *models.StockData) {
+	t.Helper()
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+	if got.Name != want.Name || got.Code != want.Code || got.Status != want.Status || got.Currency != want.Currency {
+		t.Errorf("got %s %s %s %s, want %s %s %s %s",
+			got.Name, got.Code, got.Status, got.Currency, want.Name, want.Code, want.Status, want.Currency)
+	}
+	if !near(got.Price, want.Price) || !near(got.PrevClose, want.PrevClose) ||
+		!near(got.Change, want.Change) || !near(got.ChangePct, want.ChangePct) {
+		t.Errorf("price %.2f prev %.2f change %.2f pct %.4f, want %.2f %.2f %.2f %.4f",
+			got.Price, got.PrevClose, got.Change, got.ChangePct, want.Price, want.PrevClose, want.Change, want.ChangePct)
+	}
+	if got.Volume != want.Volume || !near(got.Amount, want.Amount) {
+		t.Errorf("volume %d amount %.2f, want %d %.2f", got.Volume, got.Amount, want.Volume, want.Amount)
+	}
+	if got.Date != want.Date || got.Time != want.Time {
+		t.Errorf("time %s %s, want %s %s", got.Date, got.Time, want.Date, want.Time)
+	}
+}
+
+func TestParseSinaStockData(t *testing.T) {
+	tests := []struct {
+		fixture string
+		code    string
+		want    *models.StockData
+	}{
+		{
+			fixture: "sina_ashare.txt",
+			code:    "sh600519",
+			want: &models.StockData{
+				Name: "贵州茅台", Code: "sh600519", Price: 1688, PrevClose: 1700, Change: -12, ChangePct: -12.0 / 1700 * 100,
+				Volume: 2800000, Amount: 4730000000, Date: "2024-01-09", Time: "15:00:03",
+				Currency: "CNY", Status: models.StatusNormal,
+			},
+		},
+		{
+			fixture: "sina_suspended.txt",
+			code:    "sz000001",
+			want: &models.StockData{
+				Name: "平安银行", Code: "sz000001", Price: 10.5, PrevClose: 10.5,
+				Date: "2024-01-09", Time: "15:00:03", Currency: "CNY", Status: models.StatusSuspended,
+			},
+		},
+		{
+			fixture: "sina_hk.txt",
+			code:    "hk00700",
+			want: &models.StockData{
+				Name: "腾讯控股", Code: "hk00700", Price: 295, PrevClose: 292, Change: 3, ChangePct: 1.027,
+				Volume: 15380000, Amount: 4532150000, Date: "2024-01-09", Time: "16:08:19",
+				Currency: "HKD", Status: models.StatusNormal,
+			},
+		},
+		{
+			fixture: "sina_us.txt",
+			code:    "gb_aapl",
+			want: &models.StockData{
+				Name: "苹果", Code: "gb_aapl", Price: 185.14, PrevClose: 184.16, Change: 0.98, ChangePct: 0.53,
+				Volume: 46792908, Date: "2024-01-08", Time: "16:00 EST",
+				Currency: "USD", Status: models.StatusNormal,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			records := splitSinaRecords(loadQuoteFixture(t, tt.fixture))
+			record, ok := records[sinaSymbol(tt.code)]
+			if !ok {
+				t.Fatalf("no record for %s in %v", tt.code, records)
+			}
+			stock, err := parseSinaRecord(record, tt.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStock(t, stock, tt.want)
+		})
+	}
+}
+
+func TestParseSinaStockDataRejectsShortRecord(t *testing.T) {
+	if _, err := parseSinaStockData(`var hq_str_sh600519="";`, "sh600519"); err == nil {
+		t.Error("expected error for empty record")
+	}
+}
+
+func TestParseTencentStockData(t *testing.T) {
+	tests := []struct {
+		fixture string
+		code    string
+		want    *models.StockData
+	}{
+		{
+			fixture: "tencent_ashare.txt",
+			code:    "sh600519",
+			want: &models.StockData{
+				Name: "贵州茅台", Code: "sh600519", Price: 1688, PrevClose: 1700, Change: -12, ChangePct: -0.71,
+				Volume: 2800000, Amount: 473000000, Date: "2024-01-09", Time: "15:00:03",
+				Currency: "CNY", Status: models.StatusNormal,
+			},
+		},
+		{
+			fixture: "tencent_suspended.txt",
+			code:    "sz000001",
+			want: &models.StockData{
+				Name: "平安银行", Code: "sz000001", Price: 10.5, PrevClose: 10.5,
+				Date: "2024-01-09", Time: "15:00:03", Currency: "CNY", Status: models.StatusSuspended,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			records := splitTencentRecords(loadQuoteFixture(t, tt.fixture))
+			record, ok := records[tencentSymbol(tt.code)]
+			if !ok {
+				t.Fatalf("no record for %s in %v", tt.code, records)
+			}
+			stock, err := parseTencentStockData(record, tt.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStock(t, stock, tt.want)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"github.com/luckfunc/golangBot/internal/models"
+	"sync"
+)
+
+// QuoteProvider 行情数据源，负责单只查询、批量查询和代码识别
+type QuoteProvider interface {
+	// Name 返回数据源名称
+	Name() string
+	// Quote 查询单只股票行情
+	Quote(code string) (*models.StockData, error)
+	// Quotes 批量查询行情，按传入顺序返回，没有数据的代码会被跳过
+	Quotes(codes []string) ([]*models.StockData, error)
+	// ResolveCode 把 6 位数字代码补全为带市场前缀的代码，识别失败返回空字符串
+	ResolveCode(code string) string
+}
+
+var quoteProviderMu sync.RWMutex
+var quoteProvider QuoteProvider = NewFailoverQuoteProvider(
+	quoteFailoverThreshold,
+	quoteFailoverCooldown,
+	NewSinaQuoteProvider(envString("STOCK_QUOTE_BASE_URL", defaultSinaQuoteBaseURL)),
+	NewTencentQuoteProvider(envString("STOCK_TENCENT_QUOTE_BASE_URL", defaultTencentQuoteBaseURL)),
+)
+
+// SetQuoteProvider 替换全局行情数据源，例如切换供应商或在测试中接入 httptest
+func SetQuoteProvider(provider QuoteProvider) {
+	if provider == nil {
+		return
+	}
+	quoteProviderMu.Lock()
+	quoteProvider = provider
+	quoteProviderMu.Unlock()
+	// 切换数据源后旧缓存不再可信
+	sharedQuoteCache.reset()
+}
+
+func currentQuoteProvider() QuoteProvider {
+	quoteProviderMu.RLock()
+	defer quoteProviderMu.RUnlock()
+	return quoteProvider
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/luckfunc/golangBot/internal/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const quoteHistoryDirName = "quote_history"
+
+// 记录间隔和保留天数，超过保留天数的每日文件会被删除
+var quoteRecordInterval = envDuration("STOCK_RECORD_INTERVAL", time.Minute)
+var quoteRecordRetentionDays = envInt("STOCK_RECORD_RETENTION_DAYS", 30)
+
+var quoteHistoryMu sync.Mutex
+var lastRecordedQuote = make(map[string]string)
+var lastHistoryPrune string
+
+// StartQuoteRecorder 交易时段内定时记录所有群关注股票的行情快照
+func StartQuoteRecorder() {
+	interval := quoteRecordInterval
+	if interval <= 0 {
+		fmt.Println("STOCK_RECORD_INTERVAL 必须大于 0，改用 1 分钟")
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for range ticker.C {
+			recordWatchedQuotes(time.Now())
+		}
+	}()
+}
+
+func recordWatchedQuotes(now time.Time) {
+	var codes []string
+	for _, code := range watchedCodes() {
+		if isMarketOpen(code, now) {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) > 0 {
+		stocks, err := fetchQuotes(codes)
+		if err == nil {
+			_ = appendQuoteSnapshots(now, stocks)
+		}
+	}
+	pruneQuoteHistory(now)
+}
+
+// watchedCodes 所有群关注列表和定时提醒中的代码
+func watchedCodes() []string {
+	store, err := loadWatchlistStore()
+	if err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var codes []string
+	for _, group := range store.Groups {
+		for _, code := range group.Stocks {
+			if !seen[code] {
+				seen[code] = true
+				codes = append(codes, code)
+			}
+		}
+		for code := range group.StockIntervals {
+			if !seen[code] {
+				seen[code] = true
+				codes = append(codes, code)
+			}
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// appendQuoteSnapshots 追加写入当天的记录文件，行情时间没有变化（午休、停牌）的代码不重复记录
+func appendQuoteSnapshots(now time.Time, stocks []*models.StockData) error {
+	quoteHistoryMu.Lock()
+	defer quoteHistoryMu.Unlock()
+	var lines []byte
+	for _, stock := range stocks {
+		if stock.Status != "" && stock.Status != models.StatusNormal {
+			continue
+		}
+		quoteTime := quoteTimestamp(stock)
+		if lastRecordedQuote[stock.Code] == quoteTime {
+			continue
+		}
+		data, err := json.Marshal(models.QuoteSnapshot{
+			RecordedAt: now.Format(time.RFC3339),
+			QuoteTime:  quoteTime,
+			Code:       stock.Code,
+			Price:      stock.Price,
+			Volume:     stock.Volume,
+			Amount:     stock.Amount,
+		})
+		if err != nil {
+			continue
+		}
+		lines = append(append(lines, data...), '\n')
+		lastRecordedQuote[stock.Code] = quoteTime
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(quoteHistoryDir(), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(quoteHistoryFilePath(now), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(lines)
+	return err
+}
+
+// pruneQuoteHistory 每天清理一次超过保留天数的记录文件
+func pruneQuoteHistory(now time.Time) {
+	quoteHistoryMu.Lock()
+	defer quoteHistoryMu.Unlock()
+	today := now.Format("2006-01-02")
+	if lastHistoryPrune == today || quoteRecordRetentionDays <= 0 {
+		return
+	}
+	lastHistoryPrune = today
+	cutoff := now.AddDate(0, 0, -quoteRecordRetentionDays).Format("2006-01-02")
+	entries, err := os.ReadDir(quoteHistoryDir())
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		date := strings.TrimSuffix(entry.Name(), ".jsonl")
+		if entry.IsDir() || date == entry.Name() || date >= cutoff {
+			continue
+		}
+		_ = os.Remove(filepath.Join(quoteHistoryDir(), entry.Name()))
+	}
+}
+
+// loadQuoteSnapshots 读取某只股票在 [from, to] 日期范围内的记录，按记录时间排列
+func loadQuoteSnapshots(code string, from, to time.Time) ([]models.QuoteSnapshot, error) {
+	quoteHistoryMu.Lock()
+	defer quoteHistoryMu.Unlock()
+	var out []models.QuoteSnapshot
+	for day := from; day.Format("2006-01-02") <= to.Format("2006-01-02"); day = day.AddDate(0, 0, 1) {
+		file, err := os.Open(quoteHistoryFilePath(day))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if !strings.Contains(string(line), `"code":"`+code+`"`) {
+				continue
+			}
+			var snapshot models.QuoteSnapshot
+			if json.Unmarshal(line, &snapshot) == nil {
+				out = append(out, snapshot)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// recordedMinuteSeries 分时接口不可用时，用本地记录拼出最近一个交易日的分时：
+// 每分钟取最后一条记录，成交量为累计量之差，均价为累计成交额除以累计成交量。
+// 美股交易日跨越北京时间零点，所以从前一天的记录文件读起
+func recordedMinuteSeries(code string, now time.Time) (*models.MinuteSeries, error) {
+	snapshots, err := loadQuoteSnapshots(code, now.AddDate(0, 0, -1), now)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("没有本地行情记录")
+	}
+	date, _, _ := strings.Cut(snapshots[len(snapshots)-1].QuoteTime, " ")
+	series := &models.MinuteSeries{Code: code, Date: date}
+	// 成交量是当日累计值，第一条记录之前的成交量无法分到具体分钟，第一个点记为 0
+	lastVolume := int64(-1)
+	for _, snapshot := range snapshots {
+		day, clock, ok := strings.Cut(snapshot.QuoteTime, " ")
+		if !ok || day != date || len(clock) < 5 {
+			continue
+		}
+		point := models.MinutePoint{
+			Time:     clock[:2] + clock[3:5],
+			Price:    snapshot.Price,
+			AvgPrice: snapshot.Price,
+		}
+		if lastVolume >= 0 && snapshot.Volume > lastVolume {
+			point.Volume = snapshot.Volume - lastVolume
+		}
+		if snapshot.Volume > 0 && snapshot.Amount > 0 {
+			point.AvgPrice = snapshot.Amount / float64(snapshot.Volume)
+		}
+		lastVolume = snapshot.Volume
+		if n := len(series.Points); n > 0 && series.Points[n-1].Time == point.Time {
+			point.Volume += series.Points[n-1].Volume
+			series.Points[n-1] = point
+			continue
+		}
+		series.Points = append(series.Points, point)
+	}
+	if len(series.Points) == 0 {
+		return nil, fmt.Errorf("没有本地行情记录")
+	}
+	if stock, err := getStockData(code); err == nil {
+		series.Name = stock.Name
+		series.PrevClose = stock.PrevClose
+	}
+	if series.PrevClose == 0 {
+		series.PrevClose = series.Points[0].Price
+	}
+	return series, nil
+}
+
+func quoteHistoryDir() string {
+	return filepath.Join(".", quoteHistoryDirName)
+}
+
+func quoteHistoryFilePath(day time.Time) string {
+	return filepath.Join(quoteHistoryDir(), day.Format("2006-01-02")+".jsonl")
+}
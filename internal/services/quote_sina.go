@@ -0,0 +1,380 @@
+package services
+
+import (
+	"fmt"
+	"github.com/luckfunc/golangBot/internal/models"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultSinaQuoteBaseURL = "http://hq.sinajs.cn"
+const sinaQuoteReferer = "https://finance.sina.com.cn"
+
+// 单次 list 请求最多携带的代码数量，避免 URL 过长
+const sinaQuoteBatchSize = 30
+
+// SinaQuoteProvider 新浪财经行情接口
+type SinaQuoteProvider struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewSinaQuoteProvider 创建新浪行情数据源，baseURL 为空时使用 hq.sinajs.cn
+func NewSinaQuoteProvider(baseURL string) *SinaQuoteProvider {
+	if baseURL == "" {
+		baseURL = defaultSinaQuoteBaseURL
+	}
+	return &SinaQuoteProvider{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *SinaQuoteProvider) Name() string {
+	return "sina"
+}
+
+func (p *SinaQuoteProvider) Quote(code string) (*models.StockData, error) {
+	body, err := p.fetch(sinaSymbol(code))
+	if err != nil {
+		return nil, err
+	}
+	return parseSinaRecord(body, code)
+}
+
+// Quotes 把代码按 sinaQuoteBatchSize 分批，并发请求后按传入顺序合并结果
+func (p *SinaQuoteProvider) Quotes(codes []string) ([]*models.StockData, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	symbols := make([]string, 0, len(codes))
+	for _, code := range codes {
+		symbols = append(symbols, sinaSymbol(code))
+	}
+	chunks := chunkStrings(symbols, sinaQuoteBatchSize)
+	bodies := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk []string) {
+			defer wg.Done()
+			bodies[i], errs[i] = p.fetch(strings.Join(chunk, ","))
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	records := make(map[string]string)
+	var lastErr error
+	for i := range chunks {
+		if errs[i] != nil {
+			lastErr = errs[i]
+			continue
+		}
+		for code, record := range splitSinaRecords(bodies[i]) {
+			records[code] = record
+		}
+	}
+	var stocks []*models.StockData
+	for _, code := range codes {
+		record, ok := records[sinaSymbol(code)]
+		if !ok {
+			continue
+		}
+		stock, err := parseSinaRecord(record, code)
+		if err != nil {
+			continue
+		}
+		stocks = append(stocks, stock)
+	}
+	if len(stocks) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return stocks, nil
+}
+
+// ResolveCode 同时探测沪、深、北三个交易所，返回第一个有数据的代码
+func (p *SinaQuoteProvider) ResolveCode(code string) string {
+	candidates := []string{marketSH + code, marketSZ + code, marketBJ + code}
+	body, err := p.fetch(strings.Join(candidates, ","))
+	if err != nil {
+		return ""
+	}
+	records := splitSinaRecords(body)
+	for _, candidate := range candidates {
+		parts := strings.Split(records[candidate], "\"")
+		if len(parts) >= 2 && len(parts[1]) > 0 {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// fetch 请求 list 接口并把 GBK 响应转换为 UTF-8
+func (p *SinaQuoteProvider) fetch(list string) (string, error) {
+	req, err := http.NewRequest("GET", p.BaseURL+"/list="+list, nil)
+	if err != nil {
+		return "", err
+	}
+	// 新浪接口会校验 Referer，需要模拟浏览器访问
+	req.Header.Set("Referer", sinaQuoteReferer)
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("sina quote status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	decoder := simplifiedchinese.GBK.NewDecoder()
+	utf8Body, err := decoder.Bytes(body)
+	if err != nil {
+		return "", err
+	}
+	return string(utf8Body), nil
+}
+
+// sinaSymbol 返回标准代码在新浪接口中的代码，港股使用实时行情 rt_hk
+func sinaSymbol(code string) string {
+	if stockMarket(code) == marketHK {
+		return "rt_" + code
+	}
+	return code
+}
+
+// parseSinaRecord 按市场选择对应的字段解析方式
+func parseSinaRecord(record string, code string) (*models.StockData, error) {
+	switch stockMarket(code) {
+	case marketHK:
+		return parseSinaHKStockData(record, code)
+	case marketUS:
+		return parseSinaUSStockData(record, code)
+	}
+	return parseSinaStockData(record, code)
+}
+
+// splitSinaRecords 把多行响应拆分为 代码 -> 单行记录
+// 每行格式：var hq_str_sh600519="贵州茅台,...";
+func splitSinaRecords(body string) map[string]string {
+	records := make(map[string]string)
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "var hq_str_") {
+			continue
+		}
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			continue
+		}
+		code := strings.TrimPrefix(line[:eq], "var hq_str_")
+		records[code] = line
+	}
+	return records
+}
+
+func chunkStrings(values []string, size int) [][]string {
+	var chunks [][]string
+	for len(values) > size {
+		chunks = append(chunks, values[:size])
+		values = values[size:]
+	}
+	if len(values) > 0 {
+		chunks = append(chunks, values)
+	}
+	return chunks
+}
+
+// 解析 A 股行情记录，字段依次为：
+// 0 名称 1 今开 2 昨收 3 现价 4 最高 5 最低 6 买一价 7 卖一价 8 成交量(股) 9 成交额(元)
+// 10-19 买一至买五（量、价交替） 20-29 卖一至卖五（量、价交替） 30 日期 31 时间 32 状态码
+func parseSinaStockData(data string, code string) (*models.StockData, error) {
+	parts := strings.Split(data, "\"")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid stock data")
+	}
+
+	values := strings.Split(parts[1], ",")
+	if len(values) < 32 {
+		return nil, fmt.Errorf("insufficient stock data")
+	}
+
+	// 清理股票名称中的 XD 标记
+	stockName := strings.ReplaceAll(values[0], "XD", "")
+	stockName = strings.TrimSpace(stockName)
+
+	// 解析价格数据
+	open := parseFloatField(values[1])
+	yesterdayClose := parseFloatField(values[2])
+	currentPrice := parseFloatField(values[3])
+	high := parseFloatField(values[4])
+	low := parseFloatField(values[5])
+
+	// 计算涨跌
+	change := currentPrice - yesterdayClose
+	changePct := change / yesterdayClose * 100
+	stock := &models.StockData{
+		Name:      stockName, // 使用清理后的名称
+		Code:      code,
+		Price:     currentPrice,
+		Change:    change,
+		ChangePct: changePct,
+		High:      high,
+		Low:       low,
+		Open:      open,
+		PrevClose: yesterdayClose,
+		Volume:    parseIntField(values[8]),
+		Amount:    parseFloatField(values[9]),
+		Bids:      parseSinaOrderLevels(values[10:20]),
+		Asks:      parseSinaOrderLevels(values[20:30]),
+		Date:      strings.TrimSpace(values[30]),
+		Time:      strings.TrimSpace(values[31]),
+		Currency:  stockCurrency(code),
+	}
+	rawStatus := ""
+	if len(values) > 32 {
+		rawStatus = values[32]
+	}
+	applyTradingStatus(stock, rawStatus)
+	return stock, nil
+}
+
+// 解析港股行情记录，字段依次为：
+// 0 英文名 1 中文名 2 今开 3 昨收 4 最高 5 最低 6 现价 7 涨跌额 8 涨跌幅 9 买一价 10 卖一价
+// 11 成交额(港元) 12 成交量(股) 13 市盈率 14 周息率 15 52周最高 16 52周最低 17 日期 18 时间
+func parseSinaHKStockData(data string, code string) (*models.StockData, error) {
+	parts := strings.Split(data, "\"")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid stock data")
+	}
+
+	values := strings.Split(parts[1], ",")
+	if len(values) < 19 {
+		return nil, fmt.Errorf("insufficient stock data")
+	}
+
+	stockName := strings.TrimSpace(values[1])
+	if stockName == "" {
+		stockName = strings.TrimSpace(values[0])
+	}
+	stock := &models.StockData{
+		Name:      stockName,
+		Code:      code,
+		Price:     parseFloatField(values[6]),
+		Change:    parseFloatField(values[7]),
+		ChangePct: parseFloatField(values[8]),
+		High:      parseFloatField(values[4]),
+		Low:       parseFloatField(values[5]),
+		Open:      parseFloatField(values[2]),
+		PrevClose: parseFloatField(values[3]),
+		Volume:    parseIntField(values[12]),
+		Amount:    parseFloatField(values[11]),
+		Bids:      []models.OrderLevel{{Price: parseFloatField(values[9])}},
+		Asks:      []models.OrderLevel{{Price: parseFloatField(values[10])}},
+		Date:      strings.ReplaceAll(strings.TrimSpace(values[17]), "/", "-"),
+		Time:      strings.TrimSpace(values[18]),
+		Currency:  stockCurrency(code),
+	}
+	applyTradingStatus(stock, "")
+	return stock, nil
+}
+
+// parseSinaOrderLevels 解析"量,价"交替排列的五档盘口
+func parseSinaOrderLevels(values []string) []models.OrderLevel {
+	levels := make([]models.OrderLevel, 0, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		levels = append(levels, models.OrderLevel{
+			Price:  parseFloatField(values[i+1]),
+			Volume: parseIntField(values[i]),
+		})
+	}
+	return levels
+}
+
+func parseFloatField(value string) float64 {
+	parsed, _ := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	return parsed
+}
+
+// parseIntField 兼容 "1200.000" 这类带小数的整数字段
+func parseIntField(value string) int64 {
+	return int64(parseFloatField(value))
+}
+
+// 解析美股行情记录，字段依次为：
+// 0 名称 1 现价 2 涨跌幅 3 更新时间(北京) 4 涨跌额 5 今开 6 最高 7 最低 8 52周最高 9 52周最低
+// 10 成交量 11 10日均量 12 总市值 13 每股收益 14 市盈率 ... 25 美东时间 26 昨收
+func parseSinaUSStockData(data string, code string) (*models.StockData, error) {
+	parts := strings.Split(data, "\"")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid stock data")
+	}
+
+	values := strings.Split(parts[1], ",")
+	if len(values) < 27 {
+		return nil, fmt.Errorf("insufficient stock data")
+	}
+
+	date, clock := parseSinaUSSessionTime(strings.TrimSpace(values[25]), strings.TrimSpace(values[3]))
+	stock := &models.StockData{
+		Name:      strings.TrimSpace(values[0]),
+		Code:      code,
+		Price:     parseFloatField(values[1]),
+		Change:    parseFloatField(values[4]),
+		ChangePct: parseFloatField(values[2]),
+		High:      parseFloatField(values[6]),
+		Low:       parseFloatField(values[7]),
+		Open:      parseFloatField(values[5]),
+		PrevClose: parseFloatField(values[26]),
+		Volume:    parseIntField(values[10]),
+		Date:      date,
+		Time:      clock,
+		Currency:  stockCurrency(code),
+	}
+	applyTradingStatus(stock, "")
+	return stock, nil
+}
+
+// parseSinaUSSessionTime 把美东时间 "Jan 08 04:00PM EST" 转为 2024-01-08 / 16:00 EST，
+// 年份取自北京时间的更新时间；解析失败时返回北京时间
+func parseSinaUSSessionTime(session string, beijing string) (string, string) {
+	updated, err := time.Parse("2006-01-02 15:04:05", beijing)
+	fields := strings.Fields(session)
+	if len(fields) < 4 {
+		return splitDateTime(beijing)
+	}
+	parsed, perr := time.Parse("Jan 02 03:04PM", strings.Join(fields[:3], " "))
+	if perr != nil {
+		return splitDateTime(beijing)
+	}
+	year := time.Now().Year()
+	if err == nil {
+		year = updated.Year()
+		// 北京时间已跨年而美东仍在上一年
+		if parsed.Month() > updated.Month() {
+			year--
+		}
+	}
+	parsed = parsed.AddDate(year, 0, 0)
+	return parsed.Format("2006-01-02"), parsed.Format("15:04") + " " + fields[3]
+}
+
+func splitDateTime(value string) (string, string) {
+	fields := strings.Fields(value)
+	if len(fields) < 2 {
+		return value, ""
+	}
+	return fields[0], fields[1]
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"fmt"
+	"github.com/luckfunc/golangBot/internal/models"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultTencentQuoteBaseURL = "http://qt.gtimg.cn"
+
+// 单次 q= 请求最多携带的代码数量
+const tencentQuoteBatchSize = 50
+
+// TencentQuoteProvider 腾讯财经行情接口，作为新浪接口的备用数据源
+type TencentQuoteProvider struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewTencentQuoteProvider 创建腾讯行情数据源，baseURL 为空时使用 qt.gtimg.cn
+func NewTencentQuoteProvider(baseURL string) *TencentQuoteProvider {
+	if baseURL == "" {
+		baseURL = defaultTencentQuoteBaseURL
+	}
+	return &TencentQuoteProvider{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *TencentQuoteProvider) Name() string {
+	return "tencent"
+}
+
+func (p *TencentQuoteProvider) Quote(code string) (*models.StockData, error) {
+	stocks, err := p.Quotes([]string{code})
+	if err != nil {
+		return nil, err
+	}
+	if len(stocks) == 0 {
+		return nil, fmt.Errorf("no quote data for %s", code)
+	}
+	return stocks[0], nil
+}
+
+// Quotes 分批并发请求，按传入顺序合并结果
+func (p *TencentQuoteProvider) Quotes(codes []string) ([]*models.StockData, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	symbols := make([]string, 0, len(codes))
+	for _, code := range codes {
+		symbols = append(symbols, tencentSymbol(code))
+	}
+	chunks := chunkStrings(symbols, tencentQuoteBatchSize)
+	bodies := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk []string) {
+			defer wg.Done()
+			bodies[i], errs[i] = p.fetch(strings.Join(chunk, ","))
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	records := make(map[string]string)
+	var lastErr error
+	for i := range chunks {
+		if errs[i] != nil {
+			lastErr = errs[i]
+			continue
+		}
+		for symbol, record := range splitTencentRecords(bodies[i]) {
+			records[symbol] = record
+		}
+	}
+	var stocks []*models.StockData
+	for _, code := range codes {
+		record, ok := records[tencentSymbol(code)]
+		if !ok {
+			continue
+		}
+		stock, err := parseTencentStockData(record, code)
+		if err != nil {
+			continue
+		}
+		stocks = append(stocks, stock)
+	}
+	if len(stocks) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return stocks, nil
+}
+
+// ResolveCode 同时探测沪、深、北三个交易所，返回第一个有数据的代码
+func (p *TencentQuoteProvider) ResolveCode(code string) string {
+	candidates := []string{marketSH + code, marketSZ + code, marketBJ + code}
+	body, err := p.fetch(strings.Join(candidates, ","))
+	if err != nil {
+		return ""
+	}
+	records := splitTencentRecords(body)
+	for _, candidate := range candidates {
+		if _, ok := records[candidate]; ok {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// fetch 请求 q= 接口并把 GBK 响应转换为 UTF-8
+func (p *TencentQuoteProvider) fetch(list string) (string, error) {
+	req, err := http.NewRequest("GET", p.BaseURL+"/q="+list, nil)
+	if err != nil {
+		return "", err
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("tencent quote status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	decoder := simplifiedchinese.GBK.NewDecoder()
+	utf8Body, err := decoder.Bytes(body)
+	if err != nil {
+		return "", err
+	}
+	return string(utf8Body), nil
+}
+
+// tencentSymbol 返回标准代码在腾讯接口中的代码：美股 gb_aapl 对应 usAAPL，指数 gb_$dji 对应 usDJI，其它市场相同
+func tencentSymbol(code string) string {
+	if stockMarket(code) == marketUS {
+		return "us" + strings.ToUpper(strings.TrimPrefix(strings.TrimPrefix(code, marketUS), "$"))
+	}
+	return code
+}
+
+// splitTencentRecords 把多行响应拆分为 代码 -> 记录内容，无效代码（v_pv_none_match）会被忽略
+// 每行格式：v_sh600519="1~贵州茅台~600519~...";
+func splitTencentRecords(body string) map[string]string {
+	records := make(map[string]string)
+	for _, line := range strings.Split(body, ";") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "v_") {
+			continue
+		}
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			continue
+		}
+		symbol := strings.TrimPrefix(line[:eq], "v_")
+		value := strings.Trim(line[eq+1:], "\"")
+		if symbol == "pv_none_match" || value == "" {
+			continue
+		}
+		records[symbol] = value
+	}
+	return records
+}
+
+// 解析腾讯行情记录（已去掉引号），各市场共用的字段：
+// 1 名称 3 现价 4 昨收 5 今开 6 成交量 9-18 买一至买五（价、量交替） 19-28 卖一至卖五（价、量交替）
+// 30 时间 31 涨跌额 32 涨跌幅 33 最高 34 最低 37 成交额 40 停牌标记
+// A 股成交量单位为手、成交额单位为万元，港股和美股为股和原币种
+func parseTencentStockData(record string, code string) (*models.StockData, error) {
+	values := strings.Split(record, "~")
+	if len(values) < 38 {
+		return nil, fmt.Errorf("insufficient stock data")
+	}
+	market := stockMarket(code)
+	volumeUnit := 1.0
+	amountUnit := 1.0
+	if isAShareMarket(market) {
+		volumeUnit = 100
+		amountUnit = 1e4
+	}
+	date, clock := parseTencentTime(strings.TrimSpace(values[30]))
+	stock := &models.StockData{
+		Name:      strings.TrimSpace(values[1]),
+		Code:      code,
+		Price:     parseFloatField(values[3]),
+		Change:    parseFloatField(values[31]),
+		ChangePct: parseFloatField(values[32]),
+		High:      parseFloatField(values[33]),
+		Low:       parseFloatField(values[34]),
+		Open:      parseFloatField(values[5]),
+		PrevClose: parseFloatField(values[4]),
+		Volume:    int64(parseFloatField(values[6]) * volumeUnit),
+		Amount:    parseFloatField(values[37]) * amountUnit,
+		Date:      date,
+		Time:      clock,
+		Currency:  stockCurrency(code),
+	}
+	if isAShareMarket(market) {
+		stock.Bids = parseTencentOrderLevels(values[9:19], volumeUnit)
+		stock.Asks = parseTencentOrderLevels(values[19:29], volumeUnit)
+	}
+	applyTradingStatus(stock, tencentTradingStatus(values))
+	return stock, nil
+}
+
+// tencentTradingStatus 把腾讯记录中的停牌标记（S 或“停牌”）换算为新浪的状态码，其它情况返回空字符串
+func tencentTradingStatus(values []string) string {
+	if len(values) <= 40 {
+		return ""
+	}
+	switch flag := strings.TrimSpace(values[40]); {
+	case flag == "S", strings.Contains(flag, "停牌"):
+		return "01"
+	}
+	return ""
+}
+
+// parseTencentOrderLevels 解析"价,量"交替排列的五档盘口
+func parseTencentOrderLevels(values []string, volumeUnit float64) []models.OrderLevel {
+	levels := make([]models.OrderLevel, 0, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		levels = append(levels, models.OrderLevel{
+			Price:  parseFloatField(values[i]),
+			Volume: int64(parseFloatField(values[i+1]) * volumeUnit),
+		})
+	}
+	return levels
+}
+
+// parseTencentTime 兼容 20240109150003、2024/01/09 16:08:19 和 2024-01-08 16:00:00 三种格式
+func parseTencentTime(value string) (string, string) {
+	for _, layout := range []string{"20060102150405", "2006/01/02 15:04:05", "2006-01-02 15:04:05"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.Format("2006-01-02"), parsed.Format("15:04:05")
+		}
+	}
+	return splitDateTime(value)
+}
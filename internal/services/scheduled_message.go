@@ -0,0 +1,518 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/eatmoreapple/openwechat"
+	"github.com/luckfunc/golangBot/internal/models"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const scheduledMessageFileName = "scheduled_messages.json"
+
+// 定时消息任务的 ID 前缀，与关注列表推送任务分开同步
+const scheduledMessageJobPrefix = "message:"
+
+// 每个群最多保留的定时消息
+const maxScheduledMessages = 20
+
+const (
+	repeatDaily      = "daily"
+	repeatWeekday    = "weekday"
+	repeatTradingDay = "trading_day"
+	repeatOnce       = "once"
+)
+
+const (
+	messageKindText  = "text"
+	messageKindImage = "image"
+)
+
+var repeatNames = map[string]string{
+	repeatDaily:      "每天",
+	repeatWeekday:    "每个工作日",
+	repeatTradingDay: "每个交易日",
+	repeatOnce:       "仅一次",
+}
+
+var scheduledMessageMu sync.Mutex
+
+// scheduledImageClient 下载定时图片，只连接公网地址：在实际拨号时检查解析出的 IP，
+// 跳转和 DNS 解析到内网的域名同样会被拒绝；不走代理，否则检查的是代理地址
+var scheduledImageClient = &http.Client{
+	Timeout: 20 * time.Second,
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+					return fmt.Errorf("图片地址不能指向本机或内网：%s", host)
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+// 定时图片的下载上限
+const maxScheduledImageBytes = 10 << 20
+
+// StartScheduledMessages 注册各群的定时消息，调度器由 StartStockScheduler 启动。
+// 停机期间错过的一次性消息：仍在补发窗口（STOCK_PUSH_GRACE）内的立即补发，已过期的删除
+func StartScheduledMessages() {
+	store, err := loadScheduledMessageStore()
+	if err != nil {
+		fmt.Println("load scheduled messages:", err)
+		return
+	}
+	syncScheduledMessageJobs(store)
+	now := time.Now()
+	due, expired := missedOnceMessages(store, now)
+	for groupID, ids := range expired {
+		for _, id := range ids {
+			if _, err := removeScheduledMessage(groupID, id); err != nil {
+				fmt.Println("remove scheduled message:", err)
+			}
+		}
+	}
+	go func() {
+		for groupID, ids := range due {
+			for _, id := range ids {
+				runScheduledMessage(groupID, id, now)
+			}
+		}
+	}()
+}
+
+// missedOnceMessages 找出发送时间已过的一次性消息，按是否仍在补发窗口内分开
+func missedOnceMessages(store *models.ScheduledMessageStore, now time.Time) (map[string][]int, map[string][]int) {
+	due := make(map[string][]int)
+	expired := make(map[string][]int)
+	for groupID, messages := range store.Groups {
+		for _, message := range messages {
+			if message.Repeat != repeatOnce {
+				continue
+			}
+			at, err := time.ParseInLocation("2006-01-02 15:04", message.Date+" "+message.Time, now.Location())
+			if err != nil || now.Sub(at) > dailyPushGrace {
+				expired[groupID] = append(expired[groupID], message.ID)
+			} else if now.After(at) {
+				due[groupID] = append(due[groupID], message.ID)
+			}
+		}
+	}
+	return due, expired
+}
+
+func loadScheduledMessageStore() (*models.ScheduledMessageStore, error) {
+	data, err := os.ReadFile(scheduledMessageFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &models.ScheduledMessageStore{
+				Version: 1,
+				NextID:  1,
+				Groups:  make(map[string][]*models.ScheduledMessage),
+			}, nil
+		}
+		return nil, err
+	}
+	var store models.ScheduledMessageStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, err
+	}
+	if store.Groups == nil {
+		store.Groups = make(map[string][]*models.ScheduledMessage)
+	}
+	if store.NextID == 0 {
+		store.NextID = 1
+	}
+	return &store, nil
+}
+
+func saveScheduledMessageStore(store *models.ScheduledMessageStore) error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(scheduledMessageFilePath(), data, 0644); err != nil {
+		return err
+	}
+	syncScheduledMessageJobs(store)
+	return nil
+}
+
+func scheduledMessageFilePath() string {
+	return filepath.Join(".", scheduledMessageFileName)
+}
+
+// syncScheduledMessageJobs 按保存的定时消息重新生成调度任务
+func syncScheduledMessageJobs(store *models.ScheduledMessageStore) {
+	var jobs []*ScheduledJob
+	for groupID, messages := range store.Groups {
+		for _, message := range messages {
+			jobs = append(jobs, scheduledMessageJob(groupID, message))
+		}
+	}
+	if err := jobScheduler.ReplaceJobs(scheduledMessageJobPrefix, jobs); err != nil {
+		fmt.Println("sync scheduled messages:", err)
+	}
+}
+
+func scheduledMessageJob(groupID string, message *models.ScheduledMessage) *ScheduledJob {
+	minute := parseHHMM(message.Time)
+	job := &ScheduledJob{
+		ID:      fmt.Sprintf("%s%s:%d", scheduledMessageJobPrefix, groupID, message.ID),
+		GroupID: groupID,
+		Name:    fmt.Sprintf("定时消息 #%d", message.ID),
+		Spec:    fmt.Sprintf("%d %d * * *", minute%60, minute/60),
+		Desc:    describeScheduledMessage(message),
+	}
+	switch message.Repeat {
+	case repeatWeekday:
+		job.Spec = fmt.Sprintf("%d %d * * 1-5", minute%60, minute/60)
+	case repeatTradingDay:
+		job.TradingDayOnly = true
+	case repeatOnce:
+		at, err := time.ParseInLocation("2006-01-02 15:04", message.Date+" "+message.Time, time.Local)
+		if err != nil {
+			at = time.Time{}
+		}
+		job.NextRun = func(after time.Time) time.Time {
+			if at.After(after) {
+				return at
+			}
+			return time.Time{}
+		}
+	}
+	id := message.ID
+	job.Run = func(now time.Time) {
+		if message.Repeat == repeatOnce && now.Format("2006-01-02") != message.Date {
+			return
+		}
+		runScheduledMessage(groupID, id, now)
+	}
+	return job
+}
+
+func runScheduledMessage(groupID string, id int, now time.Time) {
+	store, err := loadScheduledMessageStore()
+	if err != nil {
+		return
+	}
+	message := findScheduledMessage(store, groupID, id)
+	if message == nil {
+		return
+	}
+	key := pushSlotKey(groupID, fmt.Sprintf("%s%d", scheduledMessageJobPrefix, id))
+	if !claimPush(key, now) {
+		return
+	}
+	target, ok := pushSender.find(groupID)
+	if !ok {
+		releasePush(key, now)
+		return
+	}
+	if err := sendScheduledMessage(target, message); err != nil {
+		fmt.Println("send scheduled message:", err)
+		releasePush(key, now)
+		return
+	}
+	if message.Repeat == repeatOnce {
+		if _, err := removeScheduledMessage(groupID, id); err != nil {
+			fmt.Println("remove scheduled message:", err)
+		}
+	}
+}
+
+func sendScheduledMessage(target *openwechat.Group, message *models.ScheduledMessage) error {
+	if message.Kind != messageKindImage {
+		_, err := target.SendText(message.Content)
+		return err
+	}
+	if err := validateImageURL(message.Content); err != nil {
+		return err
+	}
+	resp, err := scheduledImageClient.Get(message.Content)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载图片失败：HTTP %d", resp.StatusCode)
+	}
+	image, err := io.ReadAll(io.LimitReader(resp.Body, maxScheduledImageBytes+1))
+	if err != nil {
+		return err
+	}
+	if len(image) > maxScheduledImageBytes {
+		return fmt.Errorf("图片超过 %dMB", maxScheduledImageBytes>>20)
+	}
+	_, err = target.SendImage(bytes.NewReader(image))
+	return err
+}
+
+// validateImageURL 图片地址只接受带主机名的 http、https 地址，主机不能是 localhost 或本机、内网 IP；
+// 域名解析到的地址在下载时由 scheduledImageClient 检查
+func validateImageURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return fmt.Errorf("图片需要填写 http 或 https 地址：%s", raw)
+	}
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("图片地址不能指向本机或内网：%s", host)
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublicIP(ip) {
+		return fmt.Errorf("图片地址不能指向本机或内网：%s", host)
+	}
+	return nil
+}
+
+// isPublicIP 排除回环、内网、链路本地（含 169.254.169.254 元数据地址）、组播和未指定地址
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+func findScheduledMessage(store *models.ScheduledMessageStore, groupID string, id int) *models.ScheduledMessage {
+	for _, message := range store.Groups[groupID] {
+		if message.ID == id {
+			return message
+		}
+	}
+	return nil
+}
+
+func addScheduledMessage(groupID string, message *models.ScheduledMessage) error {
+	scheduledMessageMu.Lock()
+	defer scheduledMessageMu.Unlock()
+	store, err := loadScheduledMessageStore()
+	if err != nil {
+		return err
+	}
+	if len(store.Groups[groupID]) >= maxScheduledMessages {
+		return fmt.Errorf("每个群最多 %d 条定时消息", maxScheduledMessages)
+	}
+	message.ID = store.NextID
+	store.NextID++
+	store.Groups[groupID] = append(store.Groups[groupID], message)
+	return saveScheduledMessageStore(store)
+}
+
+// removeScheduledMessage 删除定时消息，返回是否找到
+func removeScheduledMessage(groupID string, id int) (bool, error) {
+	scheduledMessageMu.Lock()
+	defer scheduledMessageMu.Unlock()
+	store, err := loadScheduledMessageStore()
+	if err != nil {
+		return false, err
+	}
+	messages := store.Groups[groupID]
+	for i, message := range messages {
+		if message.ID != id {
+			continue
+		}
+		store.Groups[groupID] = append(messages[:i], messages[i+1:]...)
+		if len(store.Groups[groupID]) == 0 {
+			delete(store.Groups, groupID)
+		}
+		return true, saveScheduledMessageStore(store)
+	}
+	return false, nil
+}
+
+// parseScheduleWhen 解析重复方式和日期：每天、工作日、交易日，或 今天/明天/后天/10-20/2026-10-20 只发一次
+func parseScheduleWhen(field string, now time.Time) (string, string, bool) {
+	switch field {
+	case "每天", "每日":
+		return repeatDaily, "", true
+	case "工作日", "每个工作日":
+		return repeatWeekday, "", true
+	case "交易日", "每个交易日":
+		return repeatTradingDay, "", true
+	case "今天":
+		return repeatOnce, now.Format("2006-01-02"), true
+	case "明天":
+		return repeatOnce, now.AddDate(0, 0, 1).Format("2006-01-02"), true
+	case "后天":
+		return repeatOnce, now.AddDate(0, 0, 2).Format("2006-01-02"), true
+	}
+	if day, err := time.ParseInLocation("2006-01-02", field, now.Location()); err == nil {
+		return repeatOnce, day.Format("2006-01-02"), true
+	}
+	if day, err := time.ParseInLocation("2006-01-02", fmt.Sprintf("%d-%s", now.Year(), field), now.Location()); err == nil {
+		// 只写月日时取最近的一次
+		if day.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())) {
+			day = day.AddDate(1, 0, 0)
+		}
+		return repeatOnce, day.Format("2006-01-02"), true
+	}
+	return "", "", false
+}
+
+func describeScheduledMessage(message *models.ScheduledMessage) string {
+	if message.Repeat == repeatOnce {
+		return message.Date + " " + message.Time
+	}
+	return repeatNames[message.Repeat] + " " + message.Time
+}
+
+func formatScheduledMessageContent(message *models.ScheduledMessage) string {
+	if message.Kind == messageKindImage {
+		return "[图片] " + message.Content
+	}
+	content := []rune(message.Content)
+	if len(content) > 30 {
+		return string(content[:30]) + "…"
+	}
+	return message.Content
+}
+
+// HandleScheduledMessageCommand 处理定时消息指令：定时、定时列表、定时删除、定时管理员
+func HandleScheduledMessageCommand(msg *openwechat.Message) {
+	content := strings.TrimSpace(msg.Content)
+	// 只响应“定时”后面跟空格的指令，避免聊天中以“定时”开头的普通消息被当成指令
+	isAdd := content == "定时" || strings.HasPrefix(content, "定时 ")
+	if !isAdd && !strings.HasPrefix(content, "定时列表") && !strings.HasPrefix(content, "定时删除") &&
+		!strings.HasPrefix(content, "定时管理员") {
+		return
+	}
+	groupID, _ := resolveGroupInfo(msg)
+	if groupID == "" {
+		msg.ReplyText("只支持在群聊中设置定时消息")
+		return
+	}
+	switch {
+	case strings.HasPrefix(content, "定时列表"):
+		handleScheduledMessageList(msg, groupID)
+		return
+	case strings.HasPrefix(content, "定时删除"):
+		if !requireScheduledMessageAdmin(msg, groupID) {
+			return
+		}
+		handleScheduledMessageRemove(msg, groupID, strings.TrimSpace(strings.TrimPrefix(content, "定时删除")))
+		return
+	case strings.HasPrefix(content, "定时管理员"):
+		handleScheduledMessageAdmins(msg, groupID, strings.TrimSpace(strings.TrimPrefix(content, "定时管理员")))
+		return
+	}
+	if !requireScheduledMessageAdmin(msg, groupID) {
+		return
+	}
+	handleScheduledMessageAdd(msg, groupID, strings.TrimSpace(strings.TrimPrefix(content, "定时")))
+}
+
+func handleScheduledMessageAdd(msg *openwechat.Message, groupID, args string) {
+	usage := "用法：定时 每个交易日 09:00 今天也要好好搬砖\n" +
+		"重复：每天 / 工作日 / 交易日，只发一次：今天 / 明天 / 10-20 / 2026-10-20\n" +
+		"图片：定时 每天 12:00 图片 https://example.com/a.png\n" +
+		"查看：定时列表，删除：定时删除 3，群管理员：定时管理员"
+	fields := strings.Fields(args)
+	if len(fields) < 3 {
+		msg.ReplyText(usage)
+		return
+	}
+	now := time.Now()
+	repeat, date, ok := parseScheduleWhen(fields[0], now)
+	if !ok {
+		msg.ReplyText("无法识别的重复方式：" + fields[0] + "\n" + usage)
+		return
+	}
+	hhmm, ok := parsePushTime(fields[1])
+	if !ok {
+		msg.ReplyText("时间格式不正确，例如：定时 每天 09:00 早上好")
+		return
+	}
+	if repeat == repeatOnce {
+		at, _ := time.ParseInLocation("2006-01-02 15:04", date+" "+hhmm, now.Location())
+		if !at.After(now) {
+			msg.ReplyText("发送时间已过：" + date + " " + hhmm)
+			return
+		}
+	}
+	message := &models.ScheduledMessage{
+		Repeat:    repeat,
+		Date:      date,
+		Time:      hhmm,
+		Kind:      messageKindText,
+		CreatedBy: getSenderUserName(msg),
+		CreatedAt: now.Format(time.RFC3339),
+	}
+	// 正文保留原有的换行和空格，只去掉前面的重复方式和时间
+	body := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(args, fields[0])), fields[1]))
+	if fields[2] == "图片" {
+		imageURL := strings.TrimSpace(strings.TrimPrefix(body, "图片"))
+		if err := validateImageURL(imageURL); err != nil {
+			msg.ReplyText(fmt.Sprintf("%v\n例如：定时 每天 12:00 图片 https://example.com/a.png", err))
+			return
+		}
+		message.Kind = messageKindImage
+		body = imageURL
+	}
+	message.Content = body
+	if err := addScheduledMessage(groupID, message); err != nil {
+		msg.ReplyText(fmt.Sprintf("设置失败：%v", err))
+		return
+	}
+	msg.ReplyText(fmt.Sprintf("已添加定时消息 #%d：%s\n%s", message.ID, describeScheduledMessage(message), formatScheduledMessageContent(message)))
+}
+
+func handleScheduledMessageList(msg *openwechat.Message, groupID string) {
+	store, err := loadScheduledMessageStore()
+	if err != nil {
+		msg.ReplyText(fmt.Sprintf("读取定时消息失败：%v", err))
+		return
+	}
+	messages := append([]*models.ScheduledMessage(nil), store.Groups[groupID]...)
+	if len(messages) == 0 {
+		msg.ReplyText("本群没有定时消息，可用：定时 每个交易日 09:00 今天也要好好搬砖")
+		return
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		if messages[i].Time == messages[j].Time {
+			return messages[i].ID < messages[j].ID
+		}
+		return messages[i].Time < messages[j].Time
+	})
+	lines := []string{fmt.Sprintf("本群定时消息（%d）：", len(messages))}
+	for _, message := range messages {
+		lines = append(lines, fmt.Sprintf("#%d %s｜%s", message.ID, describeScheduledMessage(message), formatScheduledMessageContent(message)))
+	}
+	msg.ReplyText(strings.Join(lines, "\n"))
+}
+
+func handleScheduledMessageRemove(msg *openwechat.Message, groupID, args string) {
+	id, err := strconv.Atoi(strings.TrimPrefix(args, "#"))
+	if err != nil {
+		msg.ReplyText("用法：定时删除 3（编号见 定时列表）")
+		return
+	}
+	found, err := removeScheduledMessage(groupID, id)
+	if err != nil {
+		msg.ReplyText(fmt.Sprintf("删除失败：%v", err))
+		return
+	}
+	if !found {
+		msg.ReplyText(fmt.Sprintf("本群没有编号 #%d 的定时消息", id))
+		return
+	}
+	msg.ReplyText(fmt.Sprintf("已删除定时消息 #%d", id))
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"github.com/luckfunc/golangBot/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMissedOnceMessages(t *testing.T) {
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, time.Local)
+	store := &models.ScheduledMessageStore{
+		Groups: map[string][]*models.ScheduledMessage{
+			"g1": {
+				{ID: 1, Repeat: repeatOnce, Date: "2026-10-16", Time: "09:30"},
+				{ID: 2, Repeat: repeatOnce, Date: "2026-10-16", Time: "08:30"},
+				{ID: 3, Repeat: repeatOnce, Date: "2026-10-16", Time: "11:00"},
+				{ID: 4, Repeat: repeatDaily, Time: "08:00"},
+			},
+			"g2": {
+				{ID: 5, Repeat: repeatOnce, Date: "2026-10-15", Time: "09:30"},
+			},
+		},
+	}
+	due, expired := missedOnceMessages(store, now)
+	if want := map[string][]int{"g1": {1}}; !reflect.DeepEqual(due, want) {
+		t.Errorf("due = %v, want %v", due, want)
+	}
+	if want := map[string][]int{"g1": {2}, "g2": {5}}; !reflect.DeepEqual(expired, want) {
+		t.Errorf("expired = %v, want %v", expired, want)
+	}
+}
+
+func TestValidateImageURLRejectsInternalHosts(t *testing.T) {
+	for _, raw := range []string{
+		"ftp://example.com/a.png",
+		"http:///a.png",
+		"http://localhost/a.png",
+		"http://127.0.0.1:8080/a.png",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/a.png",
+		"http://192.168.1.1/a.png",
+		"http://[::1]/a.png",
+		"http://0.0.0.0/a.png",
+	} {
+		if validateImageURL(raw) == nil {
+			t.Errorf("validateImageURL(%q) accepted", raw)
+		}
+	}
+	if err := validateImageURL("https://example.com/a.png"); err != nil {
+		t.Errorf("validateImageURL(example.com) = %v", err)
+	}
+}
+
+func TestScheduledImageClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	if resp, err := scheduledImageClient.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("scheduledImageClient connected to a loopback address")
+	}
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Clock 调度器使用的时钟，测试时可替换为固定时间
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// cronSchedule 解析后的 5 段 cron 表达式：分 时 日 月 周，每段用位图记录允许的取值
+type cronSchedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	// 日和周同时限定时按标准 cron 语义取并集
+	domRestricted bool
+	dowRestricted bool
+}
+
+// parseCron 支持 *、数字、a-b 区间、逗号列表和 /n 步长，周日可写作 0 或 7
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron 表达式需要 5 段：分 时 日 月 周")
+	}
+	var schedule cronSchedule
+	var err error
+	if schedule.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if schedule.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if schedule.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if schedule.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if schedule.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+	// 以 * 开头（*、*/2）的日、周字段不算限定
+	schedule.domRestricted = !strings.HasPrefix(fields[2], "*")
+	schedule.dowRestricted = !strings.HasPrefix(fields[4], "*")
+	return &schedule, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			value, err := strconv.Atoi(stepPart)
+			if err != nil || value <= 0 {
+				return 0, fmt.Errorf("cron 步长不正确：%s", part)
+			}
+			step = value
+		}
+		start, end := min, max
+		if rangePart != "*" {
+			low, high, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = strconv.Atoi(low); err != nil {
+				return 0, fmt.Errorf("cron 取值不正确：%s", part)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(high); err != nil {
+					return 0, fmt.Errorf("cron 取值不正确：%s", part)
+				}
+			} else if hasStep {
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("cron 取值超出范围 %d-%d：%s", min, max, part)
+		}
+		for value := start; value <= end; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+func (c *cronSchedule) matchesDay(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domRestricted && c.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// Matches 判断某一分钟是否命中
+func (c *cronSchedule) Matches(t time.Time) bool {
+	return c.minute&(1<<uint(t.Minute())) != 0 &&
+		c.hour&(1<<uint(t.Hour())) != 0 &&
+		c.month&(1<<uint(t.Month())) != 0 &&
+		c.matchesDay(t)
+}
+
+// Next 返回 after 之后第一个命中的分钟，按月、日、时逐级跳过不匹配的区间，最多向后查找 5 年
+func (c *cronSchedule) Next(after time.Time) time.Time {
+	t := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute(), 0, 0, after.Location()).Add(time.Minute)
+	limit := after.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// ScheduledJob 调度器中的一个任务，GroupID 为空表示系统任务
+type ScheduledJob struct {
+	ID             string
+	GroupID        string
+	Name           string
+	Spec           string
+	TradingDayOnly bool // 只在 A 股交易日执行
+	Run            func(now time.Time)
+	// NextRun 任务在 Run 中自行判断是否执行时，用它计算真实的下次执行时间
+	NextRun func(after time.Time) time.Time
+	// Desc 任务列表中代替 cron 表达式展示的说明
+	Desc     string
+	schedule *cronSchedule
+}
+
+// upcomingJob 任务及其下次执行时间
+type upcomingJob struct {
+	Job *ScheduledJob
+	At  time.Time
+}
+
+// Scheduler 按分钟触发的 cron 调度器，每个任务在单独的 goroutine 中执行，
+// 慢任务不会拖住其它任务或错过下一分钟；上一次还没执行完的任务本分钟跳过
+type Scheduler struct {
+	clock    Clock
+	mu       sync.Mutex
+	jobs     map[string]*ScheduledJob
+	running  map[string]bool
+	lastTick time.Time
+	wg       sync.WaitGroup
+}
+
+func NewScheduler(clock Clock) *Scheduler {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return &Scheduler{clock: clock, jobs: make(map[string]*ScheduledJob), running: make(map[string]bool)}
+}
+
+var jobScheduler = NewScheduler(systemClock{})
+
+// Add 注册任务，相同 ID 的任务会被替换
+func (s *Scheduler) Add(job *ScheduledJob) error {
+	schedule, err := parseCron(job.Spec)
+	if err != nil {
+		return err
+	}
+	job.schedule = schedule
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = job
+	return nil
+}
+
+// ReplaceJobs 用新的任务列表替换所有 ID 以 prefix 开头的任务，用于配置变更后整体同步
+func (s *Scheduler) ReplaceJobs(prefix string, jobs []*ScheduledJob) error {
+	for _, job := range jobs {
+		schedule, err := parseCron(job.Spec)
+		if err != nil {
+			return fmt.Errorf("%s: %w", job.ID, err)
+		}
+		job.schedule = schedule
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.jobs {
+		if strings.HasPrefix(id, prefix) {
+			delete(s.jobs, id)
+		}
+	}
+	for _, job := range jobs {
+		s.jobs[job.ID] = job
+	}
+	return nil
+}
+
+// Start 每到整分钟执行一次 Tick
+func (s *Scheduler) Start() {
+	go func() {
+		for {
+			now := s.clock.Now()
+			next := now.Truncate(time.Minute).Add(time.Minute)
+			time.Sleep(next.Sub(now))
+			s.Tick(s.clock.Now())
+		}
+	}()
+}
+
+// Tick 执行 now 所在分钟命中的任务，同一分钟只执行一次
+func (s *Scheduler) Tick(now time.Time) {
+	minute := now.Truncate(time.Minute)
+	s.mu.Lock()
+	if !minute.After(s.lastTick) {
+		s.mu.Unlock()
+		return
+	}
+	s.lastTick = minute
+	var due []*ScheduledJob
+	for _, job := range s.jobs {
+		if s.running[job.ID] {
+			continue
+		}
+		if job.schedule.Matches(minute) && (!job.TradingDayOnly || calendar().IsTradingDay(minute)) {
+			s.running[job.ID] = true
+			due = append(due, job)
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+	for _, job := range due {
+		s.wg.Add(1)
+		go s.run(job, minute)
+	}
+}
+
+// run 执行单个任务，任务 panic 时记录日志，不影响调度器和其它任务
+func (s *Scheduler) run(job *ScheduledJob, minute time.Time) {
+	defer s.wg.Done()
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("scheduled job %s panic: %v\n", job.ID, r)
+		}
+		s.mu.Lock()
+		delete(s.running, job.ID)
+		s.mu.Unlock()
+	}()
+	job.Run(minute)
+}
+
+// Upcoming 返回某个群的任务及下次执行时间，按时间先后排列
+func (s *Scheduler) Upcoming(groupID string) []upcomingJob {
+	now := s.clock.Now()
+	s.mu.Lock()
+	var out []upcomingJob
+	for _, job := range s.jobs {
+		if job.GroupID != groupID {
+			continue
+		}
+		out = append(out, upcomingJob{Job: job, At: nextJobRun(job, now)})
+	}
+	s.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].At.Equal(out[j].At) {
+			return out[i].Job.ID < out[j].Job.ID
+		}
+		return out[i].At.Before(out[j].At)
+	})
+	return out
+}
+
+// nextJobRun 下次执行时间，只在交易日执行的任务跳过休市日
+func nextJobRun(job *ScheduledJob, after time.Time) time.Time {
+	if job.NextRun != nil {
+		return job.NextRun(after)
+	}
+	next := job.schedule.Next(after)
+	for i := 0; i < 400 && job.TradingDayOnly && !next.IsZero() && !calendar().IsTradingDay(next); i++ {
+		endOfDay := time.Date(next.Year(), next.Month(), next.Day(), 23, 59, 0, 0, next.Location())
+		next = job.schedule.Next(endOfDay)
+	}
+	return next
+}
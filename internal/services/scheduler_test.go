@@ -0,0 +1,137 @@
+package services
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func at(value string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		spec string
+		ok   bool
+	}{
+		{"* * * * *", true},
+		{"*/15 9-15 * * 1-5", true},
+		{"0,30 9 1 1 7", true},
+		{"5/10 * * * *", true},
+		{"* * * *", false},
+		{"60 * * * *", false},
+		{"* 24 * * *", false},
+		{"* * 0 * *", false},
+		{"*/0 * * * *", false},
+		{"5-1 * * * *", false},
+		{"a * * * *", false},
+	}
+	for _, tt := range tests {
+		_, err := parseCron(tt.spec)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseCron(%q) error = %v, want ok %v", tt.spec, err, tt.ok)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		spec  string
+		after string
+		want  string
+	}{
+		{"*/15 * * * *", "2026-10-16 10:07", "2026-10-16 10:15"},
+		{"30 9 * * 1-5", "2026-10-16 10:00", "2026-10-19 09:30"},
+		{"0 0 31 * *", "2026-09-01 00:00", "2026-10-31 00:00"},
+		{"5/10 * * * *", "2026-10-16 10:55", "2026-10-16 11:05"},
+		{"0 12 * * 0", "2026-10-16 12:00", "2026-10-18 12:00"},
+		{"0 12 * * 7", "2026-10-16 12:00", "2026-10-18 12:00"},
+		// 日和周同时限定时取并集：每月 20 日或每周一
+		{"0 12 20 * 1", "2026-10-16 12:00", "2026-10-19 12:00"},
+		// 以 * 开头的周字段不算限定，只按日匹配
+		{"0 12 20 * */1", "2026-10-16 12:00", "2026-10-20 12:00"},
+		{"0 0 29 2 *", "2026-03-01 00:00", "2028-02-29 00:00"},
+	}
+	for _, tt := range tests {
+		schedule, err := parseCron(tt.spec)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.spec, err)
+		}
+		if got := schedule.Next(at(tt.after)); !got.Equal(at(tt.want)) {
+			t.Errorf("%q Next(%s) = %s, want %s", tt.spec, tt.after, got.Format("2006-01-02 15:04"), tt.want)
+		}
+	}
+}
+
+func TestSchedulerTickRunsOncePerMinute(t *testing.T) {
+	s := NewScheduler(&fakeClock{})
+	var runs atomic.Int32
+	if err := s.Add(&ScheduledJob{ID: "test", Spec: "* * * * *", Run: func(time.Time) { runs.Add(1) }}); err != nil {
+		t.Fatal(err)
+	}
+	now := at("2026-10-16 10:00")
+	s.Tick(now)
+	s.Tick(now.Add(30 * time.Second))
+	s.Tick(now.Add(-time.Minute))
+	s.wg.Wait()
+	if got := runs.Load(); got != 1 {
+		t.Fatalf("runs after repeated ticks = %d, want 1", got)
+	}
+	s.Tick(now.Add(time.Minute))
+	s.wg.Wait()
+	if got := runs.Load(); got != 2 {
+		t.Fatalf("runs after next minute = %d, want 2", got)
+	}
+}
+
+func TestSchedulerTradingDayOnly(t *testing.T) {
+	clock := &fakeClock{now: at("2026-10-16 16:00")}
+	s := NewScheduler(clock)
+	var runs atomic.Int32
+	job := &ScheduledJob{ID: "test", Spec: "0 9 * * *", TradingDayOnly: true, Run: func(time.Time) { runs.Add(1) }}
+	if err := s.Add(job); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick(at("2026-10-17 09:00"))
+	s.wg.Wait()
+	if got := runs.Load(); got != 0 {
+		t.Fatalf("runs on Saturday = %d, want 0", got)
+	}
+	s.Tick(at("2026-10-19 09:00"))
+	s.wg.Wait()
+	if got := runs.Load(); got != 1 {
+		t.Fatalf("runs on Monday = %d, want 1", got)
+	}
+	upcoming := s.Upcoming("")
+	if len(upcoming) != 1 || !upcoming[0].At.Equal(at("2026-10-19 09:00")) {
+		t.Fatalf("Upcoming = %+v, want Monday 09:00", upcoming)
+	}
+}
+
+func TestSchedulerRecoversPanickingJob(t *testing.T) {
+	s := NewScheduler(&fakeClock{})
+	var runs atomic.Int32
+	_ = s.Add(&ScheduledJob{ID: "a", Spec: "* * * * *", Run: func(time.Time) { panic("boom") }})
+	_ = s.Add(&ScheduledJob{ID: "b", Spec: "* * * * *", Run: func(time.Time) { runs.Add(1) }})
+	now := at("2026-10-16 10:00")
+	s.Tick(now)
+	s.wg.Wait()
+	s.Tick(now.Add(time.Minute))
+	s.wg.Wait()
+	if got := runs.Load(); got != 2 {
+		t.Fatalf("runs = %d, want 2", got)
+	}
+}
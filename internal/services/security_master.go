@@ -0,0 +1,367 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/luckfunc/golangBot/internal/models"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const securityMasterFileName = "security_master.json"
+const defaultSinaMarketCenterBaseURL = "http://vip.stock.finance.sina.com.cn"
+
+const (
+	securityTypeStock = "stock"
+	securityTypeIndex = "index"
+	securityTypeETF   = "etf"
+
+	securityListed   = "listed"
+	securityDelisted = "delisted"
+)
+
+// SecurityListProvider 提供全市场证券列表，用于刷新本地代码表
+type SecurityListProvider interface {
+	Name() string
+	ListSecurities() ([]*models.Security, error)
+}
+
+var securityListProvider SecurityListProvider = NewSinaSecurityListProvider(envString("STOCK_MARKET_CENTER_BASE_URL", defaultSinaMarketCenterBaseURL))
+
+// securityIndex 代码表的内存索引，首次使用时从文件加载
+type securityIndex struct {
+	master   *models.SecurityMaster
+	byCode   map[string]*models.Security
+	byDigits map[string][]*models.Security
+}
+
+var securityMu sync.Mutex
+var securityRefreshMu sync.Mutex
+var loadedSecurities *securityIndex
+
+func securityMasterFilePath() string {
+	return filepath.Join(".", securityMasterFileName)
+}
+
+func loadSecurityMaster() (*models.SecurityMaster, error) {
+	data, err := os.ReadFile(securityMasterFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &models.SecurityMaster{Version: 1}, nil
+		}
+		return nil, err
+	}
+	var master models.SecurityMaster
+	if err := json.Unmarshal(data, &master); err != nil {
+		return nil, err
+	}
+	if master.Version == 0 {
+		master.Version = 1
+	}
+	return &master, nil
+}
+
+func saveSecurityMaster(master *models.SecurityMaster) error {
+	data, err := json.MarshalIndent(master, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(securityMasterFilePath(), data, 0644)
+}
+
+func newSecurityIndex(master *models.SecurityMaster) *securityIndex {
+	index := &securityIndex{
+		master:   master,
+		byCode:   make(map[string]*models.Security, len(master.Securities)),
+		byDigits: make(map[string][]*models.Security),
+	}
+	for _, security := range master.Securities {
+		index.byCode[security.Code] = security
+		if isAShareMarket(security.Exchange) {
+			digits := security.Code[len(security.Exchange):]
+			index.byDigits[digits] = append(index.byDigits[digits], security)
+		}
+	}
+	return index
+}
+
+// securities 返回内存中的代码表，读取失败时返回空表，调用方回退到在线查询
+func securities() *securityIndex {
+	securityMu.Lock()
+	defer securityMu.Unlock()
+	if loadedSecurities == nil {
+		master, err := loadSecurityMaster()
+		if err != nil {
+			fmt.Println("load security master:", err)
+			master = &models.SecurityMaster{Version: 1}
+		}
+		loadedSecurities = newSecurityIndex(master)
+	}
+	return loadedSecurities
+}
+
+// lookupSecurity 按标准代码查询代码表
+func lookupSecurity(code string) *models.Security {
+	return securities().byCode[code]
+}
+
+// resolveDigitsFromMaster 用代码表判断 6 位数字所属交易所，同号的股票和指数优先股票
+func resolveDigitsFromMaster(digits string) string {
+	matches := securities().byDigits[digits]
+	var best *models.Security
+	for _, security := range matches {
+		if security.Status == securityDelisted {
+			continue
+		}
+		if best == nil || (best.Type != securityTypeStock && security.Type == securityTypeStock) {
+			best = security
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return best.Code
+}
+
+// searchSecurityMaster 按名称或拼音首字母搜索代码表：完全一致 > 前缀匹配 > 包含，
+// exact 表示返回的都是名称或拼音首字母完全一致的结果
+func searchSecurityMaster(keyword string) (candidates []StockCandidate, exact bool) {
+	index := securities()
+	if len(index.master.Securities) == 0 {
+		return nil, false
+	}
+	lower := strings.ToLower(strings.TrimSpace(keyword))
+	if lower == "" {
+		return nil, false
+	}
+	type scored struct {
+		security *models.Security
+		score    int
+	}
+	var matches []scored
+	for _, security := range index.master.Securities {
+		if security.Status == securityDelisted {
+			continue
+		}
+		score := 0
+		switch {
+		case security.Name == keyword || security.Pinyin == lower:
+			score = 3
+		case strings.HasPrefix(security.Name, keyword) || (isLetters(lower) && strings.HasPrefix(security.Pinyin, lower)):
+			score = 2
+		case strings.Contains(security.Name, keyword):
+			score = 1
+		}
+		if score > 0 {
+			matches = append(matches, scored{security: security, score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		// 同分时股票排在指数和基金前面
+		return matches[i].security.Type == securityTypeStock && matches[j].security.Type != securityTypeStock
+	})
+	// 存在完全一致的结果时只返回这些结果
+	if len(matches) > 0 && matches[0].score == 3 {
+		exact = true
+		count := 0
+		for count < len(matches) && matches[count].score == 3 {
+			count++
+		}
+		matches = matches[:count]
+	}
+	for _, match := range matches {
+		candidates = append(candidates, StockCandidate{Code: match.security.Code, Name: match.security.Name})
+		if len(candidates) >= maxStockCandidates {
+			break
+		}
+	}
+	return candidates, exact
+}
+
+// securityDisplayName 代码表中的名称，未收录时返回空字符串
+func securityDisplayName(code string) string {
+	if security := lookupSecurity(code); security != nil {
+		return security.Name
+	}
+	return ""
+}
+
+// formatCodesWithNames 把代码列表格式化为 "贵州茅台(sh600519), sz000001"
+func formatCodesWithNames(codes []string) string {
+	parts := make([]string, 0, len(codes))
+	for _, code := range codes {
+		parts = append(parts, formatCodeWithName(code))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatCodeWithName(code string) string {
+	if name := securityDisplayName(code); name != "" {
+		return fmt.Sprintf("%s(%s)", name, code)
+	}
+	return code
+}
+
+// refreshSecurityMaster 从证券列表数据源刷新代码表；本次未返回但之前收录的证券标记为退市
+func refreshSecurityMaster() (*models.SecurityMaster, error) {
+	securityRefreshMu.Lock()
+	defer securityRefreshMu.Unlock()
+	fetched, err := securityListProvider.ListSecurities()
+	if err != nil {
+		return nil, err
+	}
+	if len(fetched) == 0 {
+		return nil, fmt.Errorf("security list is empty")
+	}
+	previous := securities().master
+	seen := make(map[string]bool, len(fetched))
+	for _, security := range fetched {
+		seen[security.Code] = true
+	}
+	merged := fetched
+	for _, security := range previous.Securities {
+		if seen[security.Code] {
+			continue
+		}
+		delisted := *security
+		delisted.Status = securityDelisted
+		merged = append(merged, &delisted)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Code < merged[j].Code })
+	master := &models.SecurityMaster{
+		Version:    1,
+		Source:     securityListProvider.Name(),
+		UpdatedAt:  time.Now().Format(time.RFC3339),
+		Securities: merged,
+	}
+	if err := saveSecurityMaster(master); err != nil {
+		return nil, err
+	}
+	securityMu.Lock()
+	loadedSecurities = newSecurityIndex(master)
+	securityMu.Unlock()
+	return master, nil
+}
+
+// SinaSecurityListProvider 新浪行情中心的分页列表接口
+type SinaSecurityListProvider struct {
+	BaseURL  string
+	Client   *http.Client
+	PageSize int
+}
+
+// NewSinaSecurityListProvider 创建新浪证券列表数据源
+func NewSinaSecurityListProvider(baseURL string) *SinaSecurityListProvider {
+	if baseURL == "" {
+		baseURL = defaultSinaMarketCenterBaseURL
+	}
+	return &SinaSecurityListProvider{
+		BaseURL:  strings.TrimRight(baseURL, "/"),
+		Client:   &http.Client{Timeout: 15 * time.Second},
+		PageSize: 100,
+	}
+}
+
+func (p *SinaSecurityListProvider) Name() string {
+	return "sina"
+}
+
+// 行情中心节点：沪深 A 股、北交所、沪深指数、ETF
+var sinaSecurityNodes = []struct {
+	node         string
+	securityType string
+}{
+	{"hs_a", securityTypeStock},
+	{"hs_bjs", securityTypeStock},
+	{"hs_s", securityTypeIndex},
+	{"etf_hq_fund", securityTypeETF},
+}
+
+func (p *SinaSecurityListProvider) ListSecurities() ([]*models.Security, error) {
+	var out []*models.Security
+	seen := make(map[string]bool)
+	for _, node := range sinaSecurityNodes {
+		for page := 1; ; page++ {
+			items, err := p.fetchPage(node.node, page)
+			if err != nil {
+				return nil, fmt.Errorf("%s page %d: %w", node.node, page, err)
+			}
+			for _, item := range items {
+				code := canonicalStockCode(item.Symbol)
+				if code == "" || seen[code] {
+					continue
+				}
+				seen[code] = true
+				out = append(out, &models.Security{
+					Code:     code,
+					Name:     strings.TrimSpace(item.Name),
+					Exchange: stockMarket(code),
+					Type:     node.securityType,
+					Status:   securityListed,
+					Pinyin:   pinyinInitials(item.Name),
+				})
+			}
+			if len(items) < p.PageSize {
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+type sinaNodeItem struct {
+	Symbol string `json:"symbol"`
+	Name   string `json:"name"`
+}
+
+func (p *SinaSecurityListProvider) fetchPage(node string, page int) ([]sinaNodeItem, error) {
+	endpoint := fmt.Sprintf("%s/quotes_service/api/json_v2.php/Market_Center.getHQNodeData?page=%d&num=%d&sort=symbol&asc=1&node=%s",
+		p.BaseURL, page, p.PageSize, node)
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Referer", sinaQuoteReferer)
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sina market center status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	// 接口可能返回 GBK 编码
+	if !utf8.Valid(body) {
+		if body, err = simplifiedchinese.GBK.NewDecoder().Bytes(body); err != nil {
+			return nil, err
+		}
+	}
+	text := strings.TrimSpace(string(body))
+	if text == "" || text == "null" {
+		return nil, nil
+	}
+	var items []sinaNodeItem
+	if err := json.Unmarshal([]byte(text), &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
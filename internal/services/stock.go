@@ -5,14 +5,13 @@ import (
 	"fmt"
 	"github.com/eatmoreapple/openwechat"
 	"github.com/luckfunc/golangBot/internal/models"
-	"golang.org/x/text/encoding/simplifiedchinese"
-	"io/ioutil"
-	"net/http"
-	"strconv"
 	"strings"
 	"time"
 )
 
+// stockQueryMaxRows 一条消息最多查询的股票数，可通过 STOCK_QUERY_MAX_ROWS 调整
+var stockQueryMaxRows = envInt("STOCK_QUERY_MAX_ROWS", 10)
+
 // HandleStockQuery 处理股票查询
 func HandleStockQuery(msg *openwechat.Message) {
 	if !shouldHandleStockInGroup(msg) {
@@ -22,23 +21,52 @@ func HandleStockQuery(msg *openwechat.Message) {
 	content := strings.TrimPrefix(msg.Content, "股票")
 	content = strings.TrimSpace(content) // 去掉可能的空格
 
-	// 从消息中提取股票代码
-	code := extractStockCode(content)
+	// 一次输入多只股票时合并成一张行情对比图
+	if fields := parseStockCodes(content); len(fields) > 1 {
+		resolution := resolveCodes(fields)
+		if len(resolution.Codes) > 1 {
+			replyMultiStockQuery(msg, resolution, messageMarketIndices(msg))
+			return
+		}
+	}
+
+	// 从消息中提取股票代码，支持名称和拼音首字母
+	lookup := extractStockLookup(content)
+	if len(lookup.Candidates) > 0 {
+		msg.ReplyText(formatCandidatesReply(lookup, "股票"))
+		return
+	}
+	code := lookup.Code
 	if code == "" {
+		for _, field := range strings.Fields(content) {
+			if suggestions := suggestSecurities(field); len(suggestions) > 0 {
+				msg.ReplyText(formatUnresolvedReply(field, suggestions))
+				return
+			}
+		}
 		msg.ReplyText("请输入正确的股票代码，例如：\n" +
-			"1. 直接输入代码：股票600519 或 股票000001")
+			"1. 直接输入代码：股票600519 或 股票000001\n" +
+			"2. 指数代码：股票 指数000300（与个股代码相同时需注明）\n" +
+			"3. 港股代码：股票00700 或 股票hk00700\n" +
+			"4. 美股代码：股票 AAPL\n" +
+			"5. 名称或拼音首字母：股票 茅台 / 股票 gzmt\n" +
+			"6. 多只对比：股票 600519 000001 300750")
 		return
 	}
 
 	// 获取股票数据
 	stock, err := getStockData(code)
 	if err != nil {
+		if suggestions := suggestSecurities(lookup.Input); len(suggestions) > 0 {
+			msg.ReplyText(formatUnresolvedReply(lookup.Input, suggestions))
+			return
+		}
 		msg.ReplyText(fmt.Sprintf("获取股票数据失败: %v", err))
 		return
 	}
 
 	// 构造回复消息
-	image, err := buildSingleStockImage(stock)
+	image, err := buildSingleStockImage(stock, messageMarketIndices(msg))
 	if err == nil {
 		_, _ = msg.ReplyImage(bytes.NewReader(image))
 		return
@@ -47,75 +75,43 @@ func HandleStockQuery(msg *openwechat.Message) {
 	msg.ReplyText(reply)
 }
 
-// 从新浪财经API获取股票数据
-func getStockData(code string) (*models.StockData, error) {
-	url := fmt.Sprintf("http://hq.sinajs.cn/list=%s", code)
-
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+// replyMultiStockQuery 多只股票合并回复，超过行数上限的代码不展示并提示
+func replyMultiStockQuery(msg *openwechat.Message, resolution stockResolution, indices []models.MarketIndex) {
+	codes := resolution.Codes
+	var notes []string
+	if limit := stockQueryMaxRows; limit > 0 && len(codes) > limit {
+		notes = append(notes, fmt.Sprintf("一次最多查询 %d 只，未展示：%s", limit, formatCodesWithNames(codes[limit:])))
+		codes = codes[:limit]
 	}
+	notes = append(notes, resolution.pendingReplies("股票")...)
 
-	// 设置请求头，模拟浏览器访问
-	req.Header.Set("Referer", "https://finance.sina.com.cn")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	stocks := fetchStocksByCodes(codes)
+	if len(stocks) == 0 {
+		msg.ReplyText("获取股票数据失败，请稍后再试")
+		return
 	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	timestamp := time.Now().Format("15:04:05")
+	image, err := renderWatchlistHTMLImage("行情对比", fetchMarketIndexSnapshots(indices), stocks, timestamp)
+	if err == nil {
+		_, _ = msg.ReplyImage(bytes.NewReader(image))
+	} else {
+		notes = append([]string{fmt.Sprintf("行情对比\n%s\n更新时间：%s", formatWatchlistTable(stocks), timestamp)}, notes...)
 	}
-
-	// 将 GBK 编码转换为 UTF-8
-	decoder := simplifiedchinese.GBK.NewDecoder()
-	utf8Body, err := decoder.Bytes(body)
-	if err != nil {
-		return nil, err
+	if len(notes) > 0 {
+		msg.ReplyText(strings.Join(notes, "\n"))
 	}
-
-	return parseStockData(string(utf8Body), code)
 }
 
-// 解析股票数据
-func parseStockData(data string, code string) (*models.StockData, error) {
-	parts := strings.Split(data, "\"")
-	if len(parts) < 2 {
-		return nil, fmt.Errorf("invalid stock data")
+// 通过行情缓存获取股票数据
+func getStockData(code string) (*models.StockData, error) {
+	stocks, err := fetchQuotes([]string{code})
+	if err != nil {
+		return nil, err
 	}
-
-	values := strings.Split(parts[1], ",")
-	if len(values) < 32 {
-		return nil, fmt.Errorf("insufficient stock data")
+	if len(stocks) == 0 {
+		return nil, fmt.Errorf("no quote data for %s", code)
 	}
-
-	// 清理股票名称中的 XD 标记
-	stockName := strings.ReplaceAll(values[0], "XD", "")
-	stockName = strings.TrimSpace(stockName)
-
-	// 解析价格数据
-	currentPrice, _ := strconv.ParseFloat(values[3], 64)
-	yesterdayClose, _ := strconv.ParseFloat(values[2], 64)
-	high, _ := strconv.ParseFloat(values[4], 64)
-	low, _ := strconv.ParseFloat(values[5], 64)
-
-	// 计算涨跌
-	change := currentPrice - yesterdayClose
-	changePct := change / yesterdayClose * 100
-	// System/Library/Fonts/PingFang.ttc
-	return &models.StockData{
-		Name:      stockName, // 使用清理后的名称
-		Code:      code,
-		Price:     currentPrice,
-		Change:    change,
-		ChangePct: changePct,
-		High:      high,
-		Low:       low,
-	}, nil
+	return stocks[0], nil
 }
 
 // 格式化股票消息
@@ -130,79 +126,60 @@ func formatStockMessage(stock *models.StockData) string {
 		trend = "➖"
 	}
 
-	return fmt.Sprintf("%s %s (%s)\n"+
-		"当前价：%.2f\n"+
-		"涨跌额：%.2f\n"+
-		"涨跌幅：%.2f%%\n"+
-		"最高价：%.2f\n"+
-		"最低价：%.2f\n"+
-		"更新时间：%s",
-		trend, stock.Name, stock.Code,
-		stock.Price,
-		stock.Change,
-		stock.ChangePct,
-		stock.High,
-		stock.Low,
-		time.Now().Format("15:04:05"))
+	if label := statusLabel(stock); label != "" {
+		return fmt.Sprintf("⏸ %s (%s) %s\n昨收：%.2f%s\n更新时间：%s",
+			stock.Name, stock.Code, label,
+			stock.PrevClose, formatCurrencySuffix(stock.Currency),
+			quoteTimestamp(stock))
+	}
+	lines := []string{
+		fmt.Sprintf("%s %s (%s)", trend, stock.Name, stock.Code),
+		fmt.Sprintf("当前价：%.2f%s", stock.Price, formatCurrencySuffix(stock.Currency)),
+		fmt.Sprintf("涨跌额：%.2f", stock.Change),
+		fmt.Sprintf("涨跌幅：%.2f%%", stock.ChangePct),
+		fmt.Sprintf("今开：%.2f", stock.Open),
+		fmt.Sprintf("最高价：%.2f", stock.High),
+		fmt.Sprintf("最低价：%.2f", stock.Low),
+		fmt.Sprintf("振幅：%.2f%%", stockAmplitude(stock)),
+		fmt.Sprintf("成交量：%s", formatStockVolume(stock)),
+	}
+	if stock.Amount > 0 {
+		lines = append(lines, fmt.Sprintf("成交额：%s", formatStockAmount(stock)))
+	}
+	lines = append(lines, fmt.Sprintf("更新时间：%s", quoteTimestamp(stock)))
+	return strings.Join(lines, "\n")
 }
 
-func buildSingleStockImage(stock *models.StockData) ([]byte, error) {
-	title := fmt.Sprintf("个股行情（%s）", stock.Name)
-	indices := fetchMarketIndexSnapshots()
-	return renderWatchlistHTMLImage(title, indices, []*models.StockData{stock}, time.Now().Format("15:04:05"))
+func buildSingleStockImage(stock *models.StockData, indices []models.MarketIndex) ([]byte, error) {
+	return renderStockCardHTMLImage(stock, fetchMarketIndexSnapshots(indices), quoteTimestamp(stock))
 }
 
-// 从消息中提取股票代码
-func extractStockCode(content string) string {
-	parts := strings.Fields(content)
-	for _, part := range parts {
-		// 如果直接输入 sh/sz 开头的代码
-		if strings.HasPrefix(part, "sh") || strings.HasPrefix(part, "sz") {
-			return part
-		}
-
-		// 如果是6位数字，先查询股票信息
-		if len(part) == 6 && isNumeric(part) {
-			// 同时查询沪深两市的股票信息
-			url := fmt.Sprintf("http://hq.sinajs.cn/list=sh%s,sz%s", part, part)
-			client := &http.Client{}
-			req, err := http.NewRequest("GET", url, nil)
-			if err != nil {
-				continue
-			}
-
-			req.Header.Set("Referer", "https://finance.sina.com.cn")
-			resp, err := client.Do(req)
-			if err != nil {
-				continue
-			}
-			defer resp.Body.Close()
-
-			body, err := ioutil.ReadAll(resp.Body)
-			if err != nil {
-				continue
-			}
+// quoteTimestamp 优先使用行情自带的日期时间，缺失时使用当前时间
+func quoteTimestamp(stock *models.StockData) string {
+	if stock.Date != "" && stock.Time != "" {
+		return stock.Date + " " + stock.Time
+	}
+	return time.Now().Format("15:04:05")
+}
 
-			// 将 GBK 编码转换为 UTF-8
-			decoder := simplifiedchinese.GBK.NewDecoder()
-			utf8Body, err := decoder.Bytes(body)
-			if err != nil {
-				continue
-			}
+// formatCurrencySuffix 非人民币价格后追加货币标注
+func formatCurrencySuffix(currency string) string {
+	if label := currencyLabel(currency); label != "" {
+		return " " + label
+	}
+	return ""
+}
 
-			// 解析返回数据，确定是沪市还是深市
-			lines := strings.Split(string(utf8Body), "\n")
-			for _, line := range lines {
-				if strings.Contains(line, "sh"+part) && len(strings.Split(line, "\"")[1]) > 0 {
-					return "sh" + part
-				}
-				if strings.Contains(line, "sz"+part) && len(strings.Split(line, "\"")[1]) > 0 {
-					return "sz" + part
-				}
-			}
+// 从消息中提取股票，返回第一个识别成功或需要用户选择的输入
+func extractStockLookup(content string) stockLookup {
+	parts := strings.Fields(content)
+	for _, part := range parts {
+		lookup := lookupStock(part)
+		if lookup.Code != "" || len(lookup.Candidates) > 0 {
+			return lookup
 		}
 	}
-	return ""
+	return stockLookup{}
 }
 
 // 判断字符串是否为数字
@@ -215,52 +192,53 @@ func isNumeric(s string) bool {
 	return true
 }
 
-// HandleMarketOverview 处理大盘行情查询
+// HandleMarketOverview 处理大盘行情查询，展示本群设置的指数
 func HandleMarketOverview(msg *openwechat.Message) {
-	// 获取三大指数数据
-	sh, err := getStockData("sh000001") // 上证指数
-	if err != nil {
-		msg.ReplyText("获取上证指数失败")
-		return
-	}
-
-	sz, err := getStockData("sz399001") // 深证成指
-	if err != nil {
-		msg.ReplyText("获取深证成指失败")
-		return
-	}
-
-	cyb, err := getStockData("sz399006") // 创业板指
-	if err != nil {
-		msg.ReplyText("获取创业板指失败")
+	indices := messageMarketIndices(msg)
+	snapshots := fetchMarketIndexSnapshots(indices)
+	if len(snapshots) == 0 {
+		msg.ReplyText("获取大盘指数失败")
 		return
 	}
 
 	// 构造回复消息
-	reply := formatMarketOverview(sh, sz, cyb)
+	reply := formatMarketOverview(snapshots)
+	if len(snapshots) < len(indices) {
+		reply += "\n部分指数获取失败"
+	}
 	msg.ReplyText(reply)
 }
 
 // formatMarketOverview 格式化大盘概览消息
-func formatMarketOverview(sh, sz, cyb *models.StockData) string {
+func formatMarketOverview(snapshots []indexSnapshot) string {
 	// 获取整体趋势图标
+	up, down := 0, 0
+	for _, snapshot := range snapshots {
+		if snapshot.Stock.Change > 0 {
+			up++
+		} else if snapshot.Stock.Change < 0 {
+			down++
+		}
+	}
 	var overallTrend string
-	if sh.Change > 0 && sz.Change > 0 && cyb.Change > 0 {
+	if up == len(snapshots) {
 		overallTrend = "🔥 大盘全线上涨"
-	} else if sh.Change < 0 && sz.Change < 0 && cyb.Change < 0 {
+	} else if down == len(snapshots) {
 		overallTrend = "💧 大盘全线下跌"
 	} else {
 		overallTrend = "📊 大盘涨跌互现"
 	}
 
-	return fmt.Sprintf("%s\n\n"+
-		"上证指数：%.2f (%+.2f%%)\n"+
-		"深证成指：%.2f (%+.2f%%)\n"+
-		"创业板指：%.2f (%+.2f%%)\n\n"+
-		"更新时间：%s",
+	lines := make([]string, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		line := fmt.Sprintf("%s：%.2f (%+.2f%%)", snapshot.Name, snapshot.Stock.Price, snapshot.Stock.ChangePct)
+		if label := statusLabel(snapshot.Stock); label != "" {
+			line = fmt.Sprintf("%s：%.2f (%s)", snapshot.Name, snapshot.Stock.Price, label)
+		}
+		lines = append(lines, line)
+	}
+	return fmt.Sprintf("%s\n\n%s\n\n更新时间：%s",
 		overallTrend,
-		sh.Price, sh.ChangePct,
-		sz.Price, sz.ChangePct,
-		cyb.Price, cyb.ChangePct,
+		strings.Join(lines, "\n"),
 		time.Now().Format("15:04:05"))
 }
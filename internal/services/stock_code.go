@@ -0,0 +1,167 @@
+package services
+
+import (
+	"strings"
+)
+
+const (
+	marketSH = "sh"
+	marketSZ = "sz"
+	marketBJ = "bj"
+	marketHK = "hk"
+	marketUS = "gb_"
+)
+
+// stockMarket 返回标准代码所属市场
+func stockMarket(code string) string {
+	switch {
+	case strings.HasPrefix(code, marketSH):
+		return marketSH
+	case strings.HasPrefix(code, marketSZ):
+		return marketSZ
+	case strings.HasPrefix(code, marketBJ):
+		return marketBJ
+	case strings.HasPrefix(code, marketHK):
+		return marketHK
+	case strings.HasPrefix(code, marketUS):
+		return marketUS
+	}
+	return ""
+}
+
+// stockCurrency 返回行情的计价货币
+func stockCurrency(code string) string {
+	switch stockMarket(code) {
+	case marketHK:
+		return "HKD"
+	case marketUS:
+		return "USD"
+	}
+	return "CNY"
+}
+
+// isAShareMarket 沪深北三个交易所共用 A 股的行情格式和交易规则
+func isAShareMarket(market string) bool {
+	return market == marketSH || market == marketSZ || market == marketBJ
+}
+
+// canonicalStockCode 把可以直接确定市场的输入转换为标准代码：
+// sh600519 / SZ000001 / bj830799 原样小写，指数000300 / 000300指数 转为指数代码，
+// hk700 / 00700 补齐为 hk00700，hkhsi 转为 hkHSI，AAPL / gb_aapl 转为美股代码 gb_aapl，$dji 转为 gb_$dji；
+// 6 位数字需要按代码段判断交易所，返回空字符串
+func canonicalStockCode(input string) string {
+	input = strings.TrimSpace(input)
+	lower := strings.ToLower(input)
+	if digits, ok := explicitIndexDigits(lower); ok {
+		return indexCode(digits)
+	}
+	switch {
+	case strings.HasPrefix(lower, marketUS) && isUSTicker(lower[len(marketUS):]):
+		return lower
+	case len(lower) == 8 && isAShareMarket(lower[:2]) && isNumeric(lower[2:]):
+		return lower
+	case strings.HasPrefix(lower, marketHK) && len(lower) > len(marketHK):
+		return normalizeHKCode(input[len(marketHK):])
+	case len(lower) == 5 && isNumeric(lower):
+		return normalizeHKCode(lower)
+	case isUSTicker(lower):
+		return marketUS + lower
+	}
+	return ""
+}
+
+// isUSTicker 美股代码由 1-5 位字母组成，允许 BRK.B 这类带类别后缀的写法，
+// 指数以 $ 开头，如 $dji、$ixic、$inx
+func isUSTicker(symbol string) bool {
+	if index, ok := strings.CutPrefix(symbol, "$"); ok {
+		return len(index) > 0 && len(index) <= 5 && isLetters(index)
+	}
+	base, class, hasClass := strings.Cut(symbol, ".")
+	if len(base) == 0 || len(base) > 5 || !isLetters(base) {
+		return false
+	}
+	return !hasClass || (len(class) > 0 && len(class) <= 2 && isLetters(class))
+}
+
+func isLetters(s string) bool {
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeHKCode 港股数字代码补齐 5 位，指数代码（如 HSI）转大写
+func normalizeHKCode(symbol string) string {
+	if isNumeric(symbol) {
+		if len(symbol) > 5 {
+			return ""
+		}
+		return marketHK + strings.Repeat("0", 5-len(symbol)) + symbol
+	}
+	return marketHK + strings.ToUpper(symbol)
+}
+
+// resolveStockInput 识别单个输入，6 位数字按代码段推断交易所
+func resolveStockInput(input string) string {
+	if code := canonicalStockCode(input); code != "" {
+		return code
+	}
+	if len(input) == 6 && isNumeric(input) {
+		return resolveStockCode(input)
+	}
+	return ""
+}
+
+// explicitIndexDigits 识别"指数000300"、"000300指数"、"zs000300"这类显式指定指数的写法
+func explicitIndexDigits(lower string) (string, bool) {
+	for _, marker := range []string{"指数", "zs"} {
+		var digits string
+		switch {
+		case strings.HasPrefix(lower, marker):
+			digits = strings.TrimPrefix(lower, marker)
+		case strings.HasSuffix(lower, marker):
+			digits = strings.TrimSuffix(lower, marker)
+		default:
+			continue
+		}
+		if len(digits) == 6 && isNumeric(digits) {
+			return digits, true
+		}
+	}
+	return "", false
+}
+
+// indexCode 399 开头的是深证指数，其余（000、880、93 等）按上证/中证指数处理
+func indexCode(digits string) string {
+	if strings.HasPrefix(digits, "399") {
+		return marketSZ + digits
+	}
+	return marketSH + digits
+}
+
+// inferExchanges 按代码段推断 6 位数字所属交易所，返回按可能性排序的候选代码。
+// 000 开头既可能是深市股票也可能是上证指数（如 000001 平安银行 / 上证指数），优先股票
+func inferExchanges(digits string) []string {
+	if len(digits) != 6 || !isNumeric(digits) {
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(digits, "000"):
+		return []string{marketSZ + digits, marketSH + digits}
+	case strings.HasPrefix(digits, "399"):
+		return []string{marketSZ + digits}
+	case strings.HasPrefix(digits, "43"), strings.HasPrefix(digits, "8"), strings.HasPrefix(digits, "92"):
+		// 北交所：43 开头的老三板转板股、8 开头的存量股票和 92 开头的新代码段
+		return []string{marketBJ + digits}
+	case strings.HasPrefix(digits, "6"), strings.HasPrefix(digits, "9"), strings.HasPrefix(digits, "5"), strings.HasPrefix(digits, "11"):
+		// 沪市：主板/科创板 6 开头，B 股 900，基金 5 开头，可转债 11 开头
+		return []string{marketSH + digits}
+	case strings.HasPrefix(digits, "0"), strings.HasPrefix(digits, "2"), strings.HasPrefix(digits, "3"),
+		strings.HasPrefix(digits, "1"):
+		// 深市：主板 00、B 股 20、创业板 30，基金 15/16/18，可转债 12
+		return []string{marketSZ + digits}
+	}
+	return nil
+}
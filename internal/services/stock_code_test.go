@@ -0,0 +1,45 @@
+package services
+
+import "testing"
+
+func TestCanonicalStockCodeUSIndex(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"$dji", "gb_$dji"},
+		{"gb_$IXIC", "gb_$ixic"},
+		{"$inx", "gb_$inx"},
+		{"AAPL", "gb_aapl"},
+		{"$", ""},
+		{"$dj1", ""},
+	}
+	for _, tt := range tests {
+		if got := canonicalStockCode(tt.input); got != tt.want {
+			t.Errorf("canonicalStockCode(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+	if got := tencentSymbol("gb_$dji"); got != "usDJI" {
+		t.Errorf("tencentSymbol(gb_$dji) = %q, want usDJI", got)
+	}
+}
+
+func TestIsMarketIndexCommand(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"股票指数", true},
+		{"股票指数 设置 沪深300 科创50", true},
+		{"股票指数 重置", true},
+		{"股票指数000300", false},
+		{"股票指数 000300", false},
+		{"股票指数设置 沪深300", false},
+		{"股票添加 600519", false},
+	}
+	for _, tt := range tests {
+		if got := isMarketIndexCommand(tt.content); got != tt.want {
+			t.Errorf("isMarketIndexCommand(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"fmt"
+	"github.com/luckfunc/golangBot/internal/models"
+	"html/template"
+	"math"
+	"strings"
+)
+
+const stockCardImageWidth = 960
+
+type stockCardStatView struct {
+	Label string
+	Value string
+	Class string
+}
+
+type stockCardLevelView struct {
+	Label  string
+	Price  string
+	Volume string
+	Class  string
+}
+
+type stockCardView struct {
+	Name      string
+	Code      string
+	Currency  string
+	Status    string
+	Price     string
+	Pct       string
+	Chg       string
+	Class     string
+	Timestamp string
+	Indices   []watchlistIndexView
+	Stats     []stockCardStatView
+	Asks      []stockCardLevelView
+	Bids      []stockCardLevelView
+}
+
+func renderStockCardHTMLImage(stock *models.StockData, indices []indexSnapshot, timestamp string) ([]byte, error) {
+	view := buildStockCardView(stock, indices, timestamp)
+	tpl, err := template.New("stock-card").Parse(stockCardHTMLTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var builder strings.Builder
+	if err := tpl.Execute(&builder, view); err != nil {
+		return nil, err
+	}
+	height := estimateStockCardHeight(len(view.Stats), len(view.Asks)+len(view.Bids), len(view.Indices))
+	return renderHTMLToPNG(builder.String(), stockCardImageWidth, height)
+}
+
+func buildStockCardView(stock *models.StockData, indices []indexSnapshot, timestamp string) stockCardView {
+	view := stockCardView{
+		Name:      stock.Name,
+		Code:      stock.Code,
+		Currency:  currencyLabel(stock.Currency),
+		Status:    statusLabel(stock),
+		Price:     fmt.Sprintf("%.2f", stock.Price),
+		Pct:       fmt.Sprintf("%+.2f%%", stock.ChangePct),
+		Chg:       fmt.Sprintf("%+.2f", stock.Change),
+		Class:     trendClass(stock.Change),
+		Timestamp: timestamp,
+		Indices:   buildIndexViews(indices),
+		Stats: []stockCardStatView{
+			{Label: "今开", Value: fmt.Sprintf("%.2f", stock.Open), Class: trendClass(stock.Open - stock.PrevClose)},
+			{Label: "昨收", Value: fmt.Sprintf("%.2f", stock.PrevClose)},
+			{Label: "最高", Value: fmt.Sprintf("%.2f", stock.High), Class: trendClass(stock.High - stock.PrevClose)},
+			{Label: "最低", Value: fmt.Sprintf("%.2f", stock.Low), Class: trendClass(stock.Low - stock.PrevClose)},
+			{Label: "振幅", Value: fmt.Sprintf("%.2f%%", stockAmplitude(stock))},
+			{Label: "成交量", Value: formatStockVolume(stock)},
+		},
+	}
+	// 美股行情没有成交额字段
+	if stock.Amount > 0 {
+		view.Stats = append(view.Stats, stockCardStatView{Label: "成交额", Value: formatStockAmount(stock)})
+	}
+	// 卖盘从卖五到卖一自上而下展示
+	for i := len(stock.Asks) - 1; i >= 0; i-- {
+		view.Asks = append(view.Asks, buildStockCardLevel(stock, fmt.Sprintf("卖%s", chineseLevelNames[i]), stock.Asks[i]))
+	}
+	for i, level := range stock.Bids {
+		view.Bids = append(view.Bids, buildStockCardLevel(stock, fmt.Sprintf("买%s", chineseLevelNames[i]), level))
+	}
+	return view
+}
+
+var chineseLevelNames = []string{"一", "二", "三", "四", "五"}
+
+func buildStockCardLevel(stock *models.StockData, label string, level models.OrderLevel) stockCardLevelView {
+	if level.Price == 0 {
+		return stockCardLevelView{Label: label, Price: "--", Volume: "--", Class: "flat"}
+	}
+	volume := "--"
+	if level.Volume > 0 {
+		volume = formatVolumeUnits(stock.Code, level.Volume)
+	}
+	return stockCardLevelView{
+		Label:  label,
+		Price:  fmt.Sprintf("%.2f", level.Price),
+		Volume: volume,
+		Class:  trendClass(level.Price - stock.PrevClose),
+	}
+}
+
+// stockAmplitude 振幅 = (最高 - 最低) / 昨收
+func stockAmplitude(stock *models.StockData) float64 {
+	if stock.PrevClose == 0 {
+		return 0
+	}
+	return (stock.High - stock.Low) / stock.PrevClose * 100
+}
+
+func formatStockVolume(stock *models.StockData) string {
+	return formatVolumeUnits(stock.Code, stock.Volume)
+}
+
+// formatVolumeUnits A 股成交量按"手"展示，其它市场按"股"展示
+func formatVolumeUnits(code string, volume int64) string {
+	if isAShareMarket(stockMarket(code)) {
+		return formatChineseUnit(float64(volume)/100) + "手"
+	}
+	return formatChineseUnit(float64(volume)) + "股"
+}
+
+func formatStockAmount(stock *models.StockData) string {
+	return formatChineseUnit(stock.Amount) + currencyUnit(stock.Currency)
+}
+
+// currencyUnit 金额单位
+func currencyUnit(currency string) string {
+	switch currency {
+	case "HKD":
+		return "港元"
+	case "USD":
+		return "美元"
+	}
+	return "元"
+}
+
+// currencyLabel 非人民币行情在价格旁标注货币
+func currencyLabel(currency string) string {
+	if currency == "" || currency == "CNY" {
+		return ""
+	}
+	return currency
+}
+
+// formatChineseUnit 按 万/亿 换算数值
+func formatChineseUnit(value float64) string {
+	abs := math.Abs(value)
+	switch {
+	case abs >= 1e8:
+		return fmt.Sprintf("%.2f亿", value/1e8)
+	case abs >= 1e4:
+		return fmt.Sprintf("%.2f万", value/1e4)
+	default:
+		return fmt.Sprintf("%.0f", value)
+	}
+}
+
+func estimateStockCardHeight(stats int, levels int, indices int) int64 {
+	const (
+		basePadding   = 80
+		headerHeight  = 120
+		indexHeight   = 34
+		statRowHeight = 64
+		levelHeight   = 34
+		footerHeight  = 40
+	)
+	height := basePadding + headerHeight + footerHeight
+	if indices > 0 {
+		height += indexHeight + 18
+	}
+	height += (stats + 3) / 4 * statRowHeight
+	if levels > 0 {
+		height += levels*levelHeight + 40
+	}
+	return int64(height)
+}
+
+const stockCardHTMLTemplate = `<!DOCTYPE html>
+<html lang="zh">
+<head>
+  <meta charset="UTF-8" />
+  <style>
+    :root {
+      --bg: #ffffff;
+      --text: #1f1f1f;
+      --muted: #6f6f6f;
+      --line: #f0f0f0;
+      --header: #f7f7f7;
+      --up: #d83a3a;
+      --down: #1ca05c;
+      --flat: #8f8f8f;
+    }
+    * { box-sizing: border-box; }
+    body {
+      margin: 0;
+      background: var(--bg);
+      font-family: "Maple Mono NF CN", "PingFang SC", "PingFang TC", "Microsoft Yahei", sans-serif;
+      color: var(--text);
+    }
+    .container {
+      width: 900px;
+      padding: 32px 40px 36px 40px;
+    }
+    .indices {
+      display: flex;
+      gap: 16px;
+      font-size: 16px;
+      color: var(--muted);
+      margin-bottom: 18px;
+      flex-wrap: wrap;
+    }
+    .name {
+      font-size: 30px;
+      font-weight: 600;
+    }
+    .name span {
+      margin-left: 12px;
+      font-size: 18px;
+      font-weight: 400;
+      color: var(--muted);
+    }
+    .quote {
+      display: flex;
+      align-items: baseline;
+      gap: 20px;
+      margin: 12px 0 20px 0;
+      font-variant-numeric: tabular-nums;
+    }
+    .quote .price { font-size: 48px; font-weight: 600; }
+    .quote .delta { font-size: 22px; }
+    .quote .status {
+      padding: 2px 10px;
+      border: 1px solid var(--flat);
+      border-radius: 4px;
+      color: var(--flat);
+    }
+    .quote .currency { margin-left: 8px; font-size: 18px; font-weight: 400; color: var(--muted); }
+    .stats {
+      display: grid;
+      grid-template-columns: repeat(4, 1fr);
+      border-top: 1px solid var(--line);
+    }
+    .stat {
+      padding: 10px 0;
+      border-bottom: 1px solid var(--line);
+    }
+    .stat .label { font-size: 14px; color: var(--muted); }
+    .stat .value { font-size: 20px; margin-top: 4px; font-variant-numeric: tabular-nums; }
+    .book {
+      width: 100%;
+      margin-top: 20px;
+      border-collapse: collapse;
+      font-size: 18px;
+      font-variant-numeric: tabular-nums;
+    }
+    .book td { padding: 5px 12px; }
+    .book td.label { color: var(--muted); width: 120px; }
+    .book tr.split td { border-top: 1px solid var(--line); }
+    .up { color: var(--up); }
+    .down { color: var(--down); }
+    .flat { color: var(--flat); }
+    .footer {
+      margin-top: 16px;
+      font-size: 14px;
+      color: var(--muted);
+    }
+  </style>
+</head>
+<body>
+  <div class="container">
+    {{if .Indices}}
+    <div class="indices">
+      <span>大盘：</span>
+      {{range .Indices}}
+        <span>{{.Name}} {{.Price}} <span class="{{.Class}}">{{.Pct}}</span></span>
+      {{end}}
+    </div>
+    {{end}}
+    <div class="name">{{.Name}}<span>{{.Code}}</span></div>
+    <div class="quote {{.Class}}">
+      <div class="price">{{.Price}}{{if .Currency}}<span class="currency">{{.Currency}}</span>{{end}}</div>
+      {{if .Status}}
+      <div class="delta status">{{.Status}}</div>
+      {{else}}
+      <div class="delta">{{.Chg}}</div>
+      <div class="delta">{{.Pct}}</div>
+      {{end}}
+    </div>
+    <div class="stats">
+      {{range .Stats}}
+        <div class="stat">
+          <div class="label">{{.Label}}</div>
+          <div class="value {{.Class}}">{{.Value}}</div>
+        </div>
+      {{end}}
+    </div>
+    {{if or .Asks .Bids}}
+    <table class="book">
+      <tbody>
+        {{range .Asks}}
+          <tr>
+            <td class="label">{{.Label}}</td>
+            <td class="{{.Class}}">{{.Price}}</td>
+            <td>{{.Volume}}</td>
+          </tr>
+        {{end}}
+        {{range $i, $level := .Bids}}
+          <tr{{if eq $i 0}} class="split"{{end}}>
+            <td class="label">{{$level.Label}}</td>
+            <td class="{{$level.Class}}">{{$level.Price}}</td>
+            <td>{{$level.Volume}}</td>
+          </tr>
+        {{end}}
+      </tbody>
+    </table>
+    {{end}}
+    <div class="footer">更新时间：{{.Timestamp}}</div>
+  </div>
+</body>
+</html>`
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"fmt"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultSinaSuggestBaseURL = "http://suggest3.sinajs.cn"
+
+// 名称搜索最多展示的候选数量
+const maxStockCandidates = 6
+
+// StockCandidate 名称搜索得到的候选股票
+type StockCandidate struct {
+	Code string
+	Name string
+}
+
+// StockSearcher 按名称、拼音首字母或简称搜索股票
+type StockSearcher interface {
+	Search(keyword string) ([]StockCandidate, error)
+}
+
+var stockSearcher StockSearcher = NewSinaStockSearcher(envString("STOCK_SUGGEST_BASE_URL", defaultSinaSuggestBaseURL))
+
+// SinaStockSearcher 新浪财经搜索联想接口，支持中文名称和拼音首字母
+type SinaStockSearcher struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewSinaStockSearcher 创建新浪搜索，baseURL 为空时使用 suggest3.sinajs.cn
+func NewSinaStockSearcher(baseURL string) *SinaStockSearcher {
+	if baseURL == "" {
+		baseURL = defaultSinaSuggestBaseURL
+	}
+	return &SinaStockSearcher{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *SinaStockSearcher) Search(keyword string) ([]StockCandidate, error) {
+	endpoint := fmt.Sprintf("%s/suggest/type=11,12,31,41&key=%s&name=suggestdata", s.BaseURL, url.QueryEscape(keyword))
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Referer", sinaQuoteReferer)
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sina suggest status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	decoder := simplifiedchinese.GBK.NewDecoder()
+	utf8Body, err := decoder.Bytes(body)
+	if err != nil {
+		return nil, err
+	}
+	return parseSinaSuggest(string(utf8Body)), nil
+}
+
+// parseSinaSuggest 解析搜索联想结果，每条记录以分号分隔，字段依次为：
+// 0 展示名 1 类型(11 A股 12 B股 31 港股 41 美股) 2 代码 3 带市场前缀的代码 4 名称
+func parseSinaSuggest(body string) []StockCandidate {
+	parts := strings.Split(body, "\"")
+	if len(parts) < 2 {
+		return nil
+	}
+	var candidates []StockCandidate
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(parts[1], ";") {
+		fields := strings.Split(item, ",")
+		if len(fields) < 5 {
+			continue
+		}
+		var code string
+		switch fields[1] {
+		case "11", "12":
+			code = canonicalStockCode(fields[3])
+		case "31":
+			code = normalizeHKCode(fields[2])
+		case "41":
+			code = marketUS + strings.ToLower(fields[2])
+		}
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		name := strings.TrimSpace(fields[4])
+		if name == "" {
+			name = strings.TrimSpace(fields[0])
+		}
+		candidates = append(candidates, StockCandidate{Code: code, Name: name})
+	}
+	return candidates
+}
+
+// stockLookup 单个输入的识别结果：Code 非空表示已确定，Candidates 多于一个表示需要用户选择
+type stockLookup struct {
+	Input      string
+	Code       string
+	Candidates []StockCandidate
+}
+
+// lookupStock 识别单个输入：先按代码规则识别，再按名称、拼音首字母搜索
+func lookupStock(input string) stockLookup {
+	if isLetters(input) {
+		return lookupLetters(input)
+	}
+	result := stockLookup{Input: input}
+	if code := resolveStockInput(input); code != "" {
+		result.Code = code
+		return result
+	}
+	if isNumeric(input) || canonicalStockCode(input) != "" {
+		return result
+	}
+	return pickCandidate(result, searchStocks(input))
+}
+
+// lookupLetters 纯字母输入既可能是美股代码也可能是拼音首字母：拼音首字母完全一致的 A 股和代码完全一致的美股
+// 优先，都没有时才采用前缀匹配的结果，仍然没有时按美股代码处理。在线搜索失败时无法确认美股代码是否存在，
+// 没有拼音完全一致的 A 股时，符合美股代码格式的输入按美股处理，避免被拼音前缀匹配的 A 股顶替
+func lookupLetters(input string) stockLookup {
+	result := stockLookup{Input: input}
+	us := canonicalStockCode(input)
+	master, masterExact := searchSecurityMaster(input)
+	online, err := stockSearcher.Search(input)
+	var exact []StockCandidate
+	if masterExact {
+		exact = append(exact, master...)
+	}
+	usFound := false
+	for _, candidate := range online {
+		if us != "" && candidate.Code == us {
+			exact = append(exact, candidate)
+			usFound = true
+			break
+		}
+	}
+	if !usFound && err != nil && us != "" && len(exact) == 0 {
+		exact = append(exact, StockCandidate{Code: us, Name: strings.ToUpper(input)})
+	}
+	switch {
+	case len(exact) == 1:
+		result.Code = exact[0].Code
+		return result
+	case len(exact) > 1:
+		// 拼音首字母和美股代码都完全一致时无法判断，交给用户选择
+		result.Candidates = limitCandidates(exact)
+		return result
+	}
+	candidates := master
+	if len(candidates) == 0 {
+		candidates = limitCandidates(online)
+	}
+	if len(candidates) == 0 {
+		result.Code = us
+		return result
+	}
+	return pickCandidate(result, candidates)
+}
+
+// pickCandidate 只有一个候选或有名称完全一致的候选时直接采用，否则交给用户选择
+func pickCandidate(result stockLookup, candidates []StockCandidate) stockLookup {
+	switch {
+	case len(candidates) == 1:
+		result.Code = candidates[0].Code
+	case len(candidates) > 1:
+		if code := pickExactCandidate(result.Input, candidates); code != "" {
+			result.Code = code
+		} else {
+			result.Candidates = candidates
+		}
+	}
+	return result
+}
+
+// searchStocks 先查本地代码表，没有结果时再调用在线搜索
+func searchStocks(keyword string) []StockCandidate {
+	if candidates, _ := searchSecurityMaster(keyword); len(candidates) > 0 {
+		return candidates
+	}
+	candidates, err := stockSearcher.Search(keyword)
+	if err != nil {
+		return nil
+	}
+	return limitCandidates(candidates)
+}
+
+func limitCandidates(candidates []StockCandidate) []StockCandidate {
+	if len(candidates) > maxStockCandidates {
+		return candidates[:maxStockCandidates]
+	}
+	return candidates
+}
+
+// pickExactCandidate 名称完全一致或美股代码完全一致时直接采用
+func pickExactCandidate(input string, candidates []StockCandidate) string {
+	lower := strings.ToLower(input)
+	for _, candidate := range candidates {
+		if candidate.Name == input || candidate.Code == marketUS+lower {
+			return candidate.Code
+		}
+	}
+	return ""
+}
+
+// formatCandidatesReply 多个候选时提示用户用代码重新发送
+func formatCandidatesReply(lookup stockLookup, example string) string {
+	lines := []string{fmt.Sprintf("「%s」匹配到多只股票，请用代码重新发送：", lookup.Input)}
+	for i, candidate := range lookup.Candidates {
+		lines = append(lines, fmt.Sprintf("%d. %s %s", i+1, candidate.Name, candidate.Code))
+	}
+	if example != "" && len(lookup.Candidates) > 0 {
+		lines = append(lines, fmt.Sprintf("例如：%s %s", example, lookup.Candidates[0].Code))
+	}
+	return strings.Join(lines, "\n")
+}
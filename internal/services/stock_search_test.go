@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"github.com/luckfunc/golangBot/internal/models"
+	"testing"
+)
+
+type fakeStockSearcher struct {
+	results map[string][]StockCandidate
+	err     error
+}
+
+func (f fakeStockSearcher) Search(keyword string) ([]StockCandidate, error) {
+	return f.results[keyword], f.err
+}
+
+func TestLookupLettersPrefersExactMatches(t *testing.T) {
+	securityMu.Lock()
+	previous := loadedSecurities
+	loadedSecurities = newSecurityIndex(&models.SecurityMaster{Securities: []*models.Security{
+		{Code: "sz000725", Name: "京东方Ａ", Exchange: "sz", Type: securityTypeStock, Pinyin: "jdfa"},
+		{Code: "sh600519", Name: "贵州茅台", Exchange: "sh", Type: securityTypeStock, Pinyin: "gzmt"},
+		{Code: "sh603589", Name: "口子窖", Exchange: "sh", Type: securityTypeStock, Pinyin: "kzj"},
+		{Code: "sz000858", Name: "五粮液", Exchange: "sz", Type: securityTypeStock, Pinyin: "wly"},
+	}})
+	securityMu.Unlock()
+	previousSearcher := stockSearcher
+	t.Cleanup(func() {
+		securityMu.Lock()
+		loadedSecurities = previous
+		securityMu.Unlock()
+		stockSearcher = previousSearcher
+	})
+
+	stockSearcher = fakeStockSearcher{results: map[string][]StockCandidate{
+		"JD": {{Code: "sz000725", Name: "京东方Ａ"}, {Code: "gb_jd", Name: "京东"}},
+	}}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"JD", "gb_jd"},
+		{"gzmt", "sh600519"},
+		{"kz", "sh603589"},
+		{"F", "gb_f"},
+	}
+	for _, tt := range tests {
+		if got := lookupStock(tt.input); got.Code != tt.want {
+			t.Errorf("lookupStock(%q) = %+v, want %s", tt.input, got, tt.want)
+		}
+	}
+
+	// 在线搜索失败时，符合美股代码格式的输入不被拼音前缀匹配的 A 股顶替
+	stockSearcher = fakeStockSearcher{err: errors.New("offline")}
+	if got := lookupStock("jdf"); got.Code != "gb_jdf" {
+		t.Errorf("lookupStock(jdf) offline = %+v, want gb_jdf", got)
+	}
+	if got := lookupStock("wly"); got.Code != "sz000858" {
+		t.Errorf("lookupStock(wly) offline = %+v, want sz000858", got)
+	}
+}
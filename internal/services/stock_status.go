@@ -0,0 +1,53 @@
+package services
+
+import (
+	"github.com/luckfunc/golangBot/internal/models"
+	"strings"
+)
+
+// applyTradingStatus 根据行情记录推断交易状态，并修正无成交时的价格和涨跌：
+// 停牌或开盘前现价为 0，直接计算会得到 -100% 的跌幅，昨收为 0 时还会出现 NaN/Inf。
+// rawStatus 为新浪 A 股记录末尾的状态码，腾讯记录的停牌标记会先换算为同样的状态码，其它市场传空字符串
+func applyTradingStatus(stock *models.StockData, rawStatus string) {
+	stock.Status = sinaTradingStatus(rawStatus)
+	if stock.Status == models.StatusNormal && stock.Price == 0 {
+		stock.Status = models.StatusNotOpened
+	}
+	if stock.Price == 0 {
+		// 无成交时以昨收作为参考价
+		stock.Price = stock.PrevClose
+	}
+	if stock.Status != models.StatusNormal || stock.PrevClose == 0 {
+		stock.Change = 0
+		stock.ChangePct = 0
+	}
+}
+
+// sinaTradingStatus 新浪 A 股状态码：00 正常，01-07 各类停牌，-2 未上市，-3 退市
+func sinaTradingStatus(raw string) models.TradingStatus {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case raw == "" || raw == "00":
+		return models.StatusNormal
+	case raw == "-3":
+		return models.StatusDelisted
+	case raw == "-2":
+		return models.StatusNotOpened
+	case strings.HasPrefix(raw, "0"):
+		return models.StatusSuspended
+	}
+	return models.StatusNormal
+}
+
+// statusLabel 非正常交易状态的展示文案，正常交易返回空字符串
+func statusLabel(stock *models.StockData) string {
+	switch stock.Status {
+	case models.StatusSuspended:
+		return "停牌"
+	case models.StatusNotOpened:
+		return "未开盘"
+	case models.StatusDelisted:
+		return "退市"
+	}
+	return ""
+}
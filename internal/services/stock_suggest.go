@@ -0,0 +1,95 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 最多给出的相近结果数量
+const maxStockSuggestions = 3
+
+// suggestSecurities 在本地代码表中查找与输入最接近的证券：
+// 数字按代码比较，其它输入按名称和拼音首字母比较，允许的编辑距离随输入长度放宽
+func suggestSecurities(input string) []StockCandidate {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil
+	}
+	index := securities()
+	lower := strings.ToLower(input)
+	digits := isNumeric(input)
+	limit := 1
+	if len([]rune(input)) >= 5 && !digits {
+		limit = 2
+	}
+	type scored struct {
+		candidate StockCandidate
+		distance  int
+	}
+	var matches []scored
+	for _, security := range index.master.Securities {
+		if security.Status == securityDelisted {
+			continue
+		}
+		distance := limit + 1
+		if digits {
+			if isAShareMarket(security.Exchange) {
+				distance = editDistance(input, security.Code[len(security.Exchange):])
+			}
+		} else {
+			distance = min(editDistance(input, security.Name), editDistance(lower, security.Pinyin))
+		}
+		if distance <= limit {
+			matches = append(matches, scored{
+				candidate: StockCandidate{Code: security.Code, Name: security.Name},
+				distance:  distance,
+			})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+	var out []StockCandidate
+	for _, match := range matches {
+		out = append(out, match.candidate)
+		if len(out) >= maxStockSuggestions {
+			break
+		}
+	}
+	return out
+}
+
+// editDistance 按字符计算 Levenshtein 编辑距离
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// formatUnresolvedReply 提示未识别的输入，并附上相近的证券
+func formatUnresolvedReply(input string, suggestions []StockCandidate) string {
+	if len(suggestions) == 0 {
+		return fmt.Sprintf("未识别：%s", input)
+	}
+	parts := make([]string, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		parts = append(parts, fmt.Sprintf("%s %s", suggestion.Name, suggestion.Code))
+	}
+	return fmt.Sprintf("未识别：%s，你是不是要找：%s", input, strings.Join(parts, " / "))
+}
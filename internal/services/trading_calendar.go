@@ -0,0 +1,489 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/eatmoreapple/openwechat"
+	"github.com/luckfunc/golangBot/internal/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const tradingCalendarFileName = "trading_calendar.json"
+
+// SessionPhase A 股一个交易日内的时段
+type SessionPhase string
+
+const (
+	PhaseClosed    SessionPhase = "closed"    // 休市或已收盘
+	PhasePreOpen   SessionPhase = "pre_open"  // 开盘集合竞价 09:15-09:30
+	PhaseMorning   SessionPhase = "morning"   // 上午连续竞价 09:30-11:30
+	PhaseLunch     SessionPhase = "lunch"     // 午间休市 11:30-13:00
+	PhaseAfternoon SessionPhase = "afternoon" // 下午交易 13:00-15:00，含收盘集合竞价
+)
+
+// A 股交易时间，单位为当日分钟数
+const (
+	preOpenStart   = 9*60 + 15
+	morningStart   = 9*60 + 30
+	morningEnd     = 11*60 + 30
+	afternoonStart = 13 * 60
+	afternoonEnd   = 15 * 60
+)
+
+// tradingCalendar 交易日历的内存索引，首次使用时从文件加载
+type tradingCalendar struct {
+	calendar *models.TradingCalendar
+	holidays map[string]models.CalendarDay
+	halfDays map[string]int // 日期 -> 收盘时间（分钟）
+}
+
+var calendarMu sync.Mutex
+var loadedCalendar *tradingCalendar
+
+// calendarWriteMu 串行化日历的修改，读取、修改、写回文件期间不会被其它修改覆盖
+var calendarWriteMu sync.Mutex
+
+func tradingCalendarFilePath() string {
+	return filepath.Join(".", tradingCalendarFileName)
+}
+
+func loadTradingCalendar() (*models.TradingCalendar, error) {
+	data, err := os.ReadFile(tradingCalendarFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &models.TradingCalendar{Version: 1}, nil
+		}
+		return nil, err
+	}
+	var calendar models.TradingCalendar
+	if err := json.Unmarshal(data, &calendar); err != nil {
+		return nil, err
+	}
+	if calendar.Version == 0 {
+		calendar.Version = 1
+	}
+	return &calendar, nil
+}
+
+func saveTradingCalendar(calendar *models.TradingCalendar) error {
+	data, err := json.MarshalIndent(calendar, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(tradingCalendarFilePath(), data, 0644)
+}
+
+func newTradingCalendar(calendar *models.TradingCalendar) *tradingCalendar {
+	index := &tradingCalendar{
+		calendar: calendar,
+		holidays: make(map[string]models.CalendarDay, len(calendar.Holidays)),
+		halfDays: make(map[string]int, len(calendar.HalfDays)),
+	}
+	for _, day := range calendar.Holidays {
+		index.holidays[day.Date] = day
+	}
+	for _, day := range calendar.HalfDays {
+		if minute := parseHHMM(day.Close); minute > 0 {
+			index.halfDays[day.Date] = minute
+		}
+	}
+	return index
+}
+
+// calendar 返回内存中的交易日历，读取失败时只按周末判断
+func calendar() *tradingCalendar {
+	calendarMu.Lock()
+	defer calendarMu.Unlock()
+	if loadedCalendar == nil {
+		loaded, err := loadTradingCalendar()
+		if err != nil {
+			fmt.Println("load trading calendar:", err)
+			loaded = &models.TradingCalendar{Version: 1}
+		}
+		loadedCalendar = newTradingCalendar(loaded)
+	}
+	return loadedCalendar
+}
+
+// updateTradingCalendar 修改日历后写回文件并刷新内存索引
+func updateTradingCalendar(update func(calendar *models.TradingCalendar)) error {
+	calendarWriteMu.Lock()
+	defer calendarWriteMu.Unlock()
+	current := calendar().calendar
+	updated := &models.TradingCalendar{
+		Version:  current.Version,
+		Holidays: append([]models.CalendarDay(nil), current.Holidays...),
+		HalfDays: append([]models.CalendarDay(nil), current.HalfDays...),
+	}
+	update(updated)
+	sort.Slice(updated.Holidays, func(i, j int) bool { return updated.Holidays[i].Date < updated.Holidays[j].Date })
+	sort.Slice(updated.HalfDays, func(i, j int) bool { return updated.HalfDays[i].Date < updated.HalfDays[j].Date })
+	if err := saveTradingCalendar(updated); err != nil {
+		return err
+	}
+	calendarMu.Lock()
+	loadedCalendar = newTradingCalendar(updated)
+	calendarMu.Unlock()
+	return nil
+}
+
+// IsTradingDay 周末和休市日以外都是交易日
+func (c *tradingCalendar) IsTradingDay(t time.Time) bool {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	_, holiday := c.holidays[t.Format("2006-01-02")]
+	return !holiday
+}
+
+// CloseMinute 当天的收盘时间（分钟），半日市提前收盘
+func (c *tradingCalendar) CloseMinute(t time.Time) int {
+	if minute, ok := c.halfDays[t.Format("2006-01-02")]; ok {
+		return minute
+	}
+	return afternoonEnd
+}
+
+// Phase 返回某一时刻所处的交易时段
+func (c *tradingCalendar) Phase(t time.Time) SessionPhase {
+	if !c.IsTradingDay(t) {
+		return PhaseClosed
+	}
+	minute := t.Hour()*60 + t.Minute()
+	closeMinute := c.CloseMinute(t)
+	switch {
+	case minute < preOpenStart || minute >= closeMinute:
+		return PhaseClosed
+	case minute < morningStart:
+		return PhasePreOpen
+	case minute < morningEnd:
+		return PhaseMorning
+	case minute < afternoonStart:
+		return PhaseLunch
+	case minute < afternoonEnd:
+		return PhaseAfternoon
+	}
+	return PhaseClosed
+}
+
+// IsOpen 连续竞价时段内返回 true；收盘时刻（15:00 或半日市收盘）也算在内，便于记录收盘价
+func (c *tradingCalendar) IsOpen(t time.Time) bool {
+	switch c.Phase(t) {
+	case PhaseMorning, PhaseAfternoon:
+		return true
+	}
+	minute := t.Hour()*60 + t.Minute()
+	return c.IsTradingDay(t) && (minute == morningEnd || minute == c.CloseMinute(t))
+}
+
+// NextTradingDay 返回 t 之后（不含当天）的第一个交易日
+func (c *tradingCalendar) NextTradingDay(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for i := 0; i < 366; i++ {
+		day = day.AddDate(0, 0, 1)
+		if c.IsTradingDay(day) {
+			return day
+		}
+	}
+	return day
+}
+
+// PrevTradingDay 返回 t 之前（不含当天）的最后一个交易日
+func (c *tradingCalendar) PrevTradingDay(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for i := 0; i < 366; i++ {
+		day = day.AddDate(0, 0, -1)
+		if c.IsTradingDay(day) {
+			return day
+		}
+	}
+	return day
+}
+
+// Holiday 返回当天的休市说明
+func (c *tradingCalendar) Holiday(t time.Time) (models.CalendarDay, bool) {
+	day, ok := c.holidays[t.Format("2006-01-02")]
+	return day, ok
+}
+
+// 美东时区只加载一次，交易时段判断每分钟都会换算
+var newYorkLocation = sync.OnceValues(func() (*time.Location, error) {
+	return time.LoadLocation("America/New_York")
+})
+
+// marketLocalTime 换算为代码所属市场的当地时间，美股为美东时间
+func marketLocalTime(code string, now time.Time) (time.Time, bool) {
+	if stockMarket(code) != marketUS {
+		return now, true
+	}
+	location, err := newYorkLocation()
+	if err != nil {
+		return now, false
+	}
+	return now.In(location), true
+}
+
+// marketSessionsOn 代码所属市场某天的交易时段，休市返回空；
+// A 股按交易日历判断休市和半日市，港股、美股只区分周末
+func marketSessionsOn(code string, local time.Time) []sessionRange {
+	if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+		return nil
+	}
+	sessions := marketSessions(code)
+	if !isAShareMarket(stockMarket(code)) {
+		return sessions
+	}
+	c := calendar()
+	if !c.IsTradingDay(local) {
+		return nil
+	}
+	closeMinute := c.CloseMinute(local)
+	var out []sessionRange
+	for _, session := range sessions {
+		if session.Start >= closeMinute {
+			break
+		}
+		if session.End > closeMinute {
+			session.End = closeMinute
+		}
+		out = append(out, session)
+	}
+	return out
+}
+
+// currentSession 返回 now 所在的交易时段（含开始、不含结束）和市场当地时间
+func currentSession(code string, now time.Time) (sessionRange, time.Time, bool) {
+	local, ok := marketLocalTime(code, now)
+	if !ok {
+		return sessionRange{}, local, false
+	}
+	minute := local.Hour()*60 + local.Minute()
+	for _, session := range marketSessionsOn(code, local) {
+		if minute >= session.Start && minute < session.End {
+			return session, local, true
+		}
+	}
+	return sessionRange{}, local, false
+}
+
+// isMarketOpen 判断代码所属市场是否在交易时段内，收盘那一分钟也算在内，便于记录收盘价
+func isMarketOpen(code string, now time.Time) bool {
+	local, ok := marketLocalTime(code, now)
+	if !ok {
+		return false
+	}
+	minute := local.Hour()*60 + local.Minute()
+	for _, session := range marketSessionsOn(code, local) {
+		if minute >= session.Start && minute <= session.End {
+			return true
+		}
+	}
+	return false
+}
+
+// phaseLabel 交易时段的中文名称
+func phaseLabel(phase SessionPhase) string {
+	switch phase {
+	case PhasePreOpen:
+		return "集合竞价"
+	case PhaseMorning:
+		return "上午交易"
+	case PhaseLunch:
+		return "午间休市"
+	case PhaseAfternoon:
+		return "下午交易"
+	}
+	return "休市"
+}
+
+var weekdayNames = []string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"}
+
+func formatTradingDate(t time.Time) string {
+	return t.Format("2006-01-02") + " " + weekdayNames[t.Weekday()]
+}
+
+// handleTradingCalendar 查看交易日历；超管可维护休市日和半日市
+func handleTradingCalendar(msg *openwechat.Message, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		msg.ReplyText(formatTradingCalendarStatus(time.Now()))
+		return
+	}
+	userName := getSenderUserName(msg)
+	if userName == "" || !superAdmins[userName] {
+		msg.ReplyText("仅超管可修改交易日历")
+		return
+	}
+	switch fields[0] {
+	case "休市":
+		handleCalendarHoliday(msg, fields[1:])
+	case "半日":
+		handleCalendarHalfDay(msg, fields[1:])
+	case "删除":
+		handleCalendarRemove(msg, fields[1:])
+	default:
+		msg.ReplyText(tradingCalendarUsage)
+	}
+}
+
+const tradingCalendarUsage = "用法：股票日历\n" +
+	"股票日历 休市 2024-10-01 2024-10-07 国庆节（结束日期和名称可省略）\n" +
+	"股票日历 半日 2024-12-31 11:30 跨年\n" +
+	"股票日历 删除 2024-10-01"
+
+func handleCalendarHoliday(msg *openwechat.Message, fields []string) {
+	if len(fields) == 0 {
+		msg.ReplyText(tradingCalendarUsage)
+		return
+	}
+	start, err := time.ParseInLocation("2006-01-02", fields[0], time.Local)
+	if err != nil {
+		msg.ReplyText(tradingCalendarUsage)
+		return
+	}
+	end := start
+	name := ""
+	if len(fields) > 1 {
+		if parsed, err := time.ParseInLocation("2006-01-02", fields[1], time.Local); err == nil {
+			end = parsed
+			name = strings.Join(fields[2:], " ")
+		} else {
+			name = strings.Join(fields[1:], " ")
+		}
+	}
+	if end.Before(start) || end.Sub(start) > 31*24*time.Hour {
+		msg.ReplyText("休市区间需在 31 天以内，且结束日期不早于开始日期")
+		return
+	}
+	var added []string
+	err = updateTradingCalendar(func(calendar *models.TradingCalendar) {
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			// 周末本来就休市，不用写入文件
+			if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+				continue
+			}
+			date := day.Format("2006-01-02")
+			calendar.Holidays = removeCalendarDay(calendar.Holidays, date)
+			calendar.Holidays = append(calendar.Holidays, models.CalendarDay{Date: date, Name: name})
+			added = append(added, date)
+		}
+	})
+	if err != nil {
+		msg.ReplyText(fmt.Sprintf("设置失败：%v", err))
+		return
+	}
+	if len(added) == 0 {
+		msg.ReplyText("所选日期都是周末，无需设置")
+		return
+	}
+	msg.ReplyText(fmt.Sprintf("已设置休市：%s", strings.Join(added, " ")))
+}
+
+func handleCalendarHalfDay(msg *openwechat.Message, fields []string) {
+	if len(fields) < 2 {
+		msg.ReplyText(tradingCalendarUsage)
+		return
+	}
+	day, err := time.ParseInLocation("2006-01-02", fields[0], time.Local)
+	closeMinute := parseHHMM(fields[1])
+	if err != nil || closeMinute <= morningStart || closeMinute >= afternoonEnd {
+		msg.ReplyText(tradingCalendarUsage)
+		return
+	}
+	date := day.Format("2006-01-02")
+	entry := models.CalendarDay{Date: date, Name: strings.Join(fields[2:], " "), Close: formatMinuteOfDay(closeMinute)}
+	err = updateTradingCalendar(func(calendar *models.TradingCalendar) {
+		calendar.HalfDays = removeCalendarDay(calendar.HalfDays, date)
+		calendar.HalfDays = append(calendar.HalfDays, entry)
+	})
+	if err != nil {
+		msg.ReplyText(fmt.Sprintf("设置失败：%v", err))
+		return
+	}
+	msg.ReplyText(fmt.Sprintf("已设置半日市：%s %s 收盘", date, entry.Close))
+}
+
+func handleCalendarRemove(msg *openwechat.Message, fields []string) {
+	if len(fields) == 0 {
+		msg.ReplyText(tradingCalendarUsage)
+		return
+	}
+	var removed []string
+	err := updateTradingCalendar(func(calendar *models.TradingCalendar) {
+		for _, date := range fields {
+			before := len(calendar.Holidays) + len(calendar.HalfDays)
+			calendar.Holidays = removeCalendarDay(calendar.Holidays, date)
+			calendar.HalfDays = removeCalendarDay(calendar.HalfDays, date)
+			if len(calendar.Holidays)+len(calendar.HalfDays) < before {
+				removed = append(removed, date)
+			}
+		}
+	})
+	if err != nil {
+		msg.ReplyText(fmt.Sprintf("删除失败：%v", err))
+		return
+	}
+	if len(removed) == 0 {
+		msg.ReplyText("日历中没有这些日期")
+		return
+	}
+	msg.ReplyText(fmt.Sprintf("已删除：%s", strings.Join(removed, " ")))
+}
+
+func removeCalendarDay(days []models.CalendarDay, date string) []models.CalendarDay {
+	out := days[:0]
+	for _, day := range days {
+		if day.Date != date {
+			out = append(out, day)
+		}
+	}
+	return out
+}
+
+// formatTradingCalendarStatus 今天的交易状态、下一个交易日和近期的休市安排
+func formatTradingCalendarStatus(now time.Time) string {
+	c := calendar()
+	var status string
+	switch {
+	case now.Weekday() == time.Saturday || now.Weekday() == time.Sunday:
+		status = "休市（周末）"
+	case !c.IsTradingDay(now):
+		holiday, _ := c.Holiday(now)
+		status = "休市"
+		if holiday.Name != "" {
+			status += "（" + holiday.Name + "）"
+		}
+	case c.CloseMinute(now) != afternoonEnd:
+		status = fmt.Sprintf("交易日（半日市，%s 收盘）", formatMinuteOfDay(c.CloseMinute(now)))
+	default:
+		status = "交易日"
+	}
+	lines := []string{
+		fmt.Sprintf("今天：%s %s", formatTradingDate(now), status),
+		fmt.Sprintf("当前时段：%s", phaseLabel(c.Phase(now))),
+		fmt.Sprintf("下一个交易日：%s", formatTradingDate(c.NextTradingDay(now))),
+	}
+	today := now.Format("2006-01-02")
+	var upcoming []string
+	for _, day := range c.calendar.Holidays {
+		if day.Date >= today && len(upcoming) < 10 {
+			upcoming = append(upcoming, strings.TrimSpace(day.Date+" "+day.Name))
+		}
+	}
+	for _, day := range c.calendar.HalfDays {
+		if day.Date >= today && len(upcoming) < 10 {
+			upcoming = append(upcoming, strings.TrimSpace(fmt.Sprintf("%s %s 收盘 %s", day.Date, day.Close, day.Name)))
+		}
+	}
+	if len(upcoming) > 0 {
+		lines = append(lines, "近期安排：\n"+strings.Join(upcoming, "\n"))
+	} else {
+		lines = append(lines, "日历中没有后续休市安排，节假日需由超管用 股票日历 休市 添加")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"encoding/json"
+	"github.com/luckfunc/golangBot/internal/models"
+	"os"
+	"testing"
+	"time"
+)
+
+// 仓库根目录随附的交易日历
+func TestShippedTradingCalendar(t *testing.T) {
+	data, err := os.ReadFile("../../" + tradingCalendarFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded models.TradingCalendar
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	index := newTradingCalendar(&loaded)
+	tests := []struct {
+		date string
+		want bool
+	}{
+		{"2026-01-02", false},
+		{"2026-01-05", true},
+		{"2026-02-23", false},
+		{"2026-02-24", true},
+		{"2026-10-07", false},
+		{"2026-10-08", true},
+		{"2026-10-10", false},
+	}
+	for _, tt := range tests {
+		day, _ := time.ParseInLocation("2006-01-02", tt.date, time.Local)
+		if got := index.IsTradingDay(day); got != tt.want {
+			t.Errorf("IsTradingDay(%s) = %v, want %v", tt.date, got, tt.want)
+		}
+	}
+}
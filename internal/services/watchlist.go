@@ -6,9 +6,6 @@ import (
 	"fmt"
 	"github.com/eatmoreapple/openwechat"
 	"github.com/luckfunc/golangBot/internal/models"
-	"golang.org/x/text/encoding/simplifiedchinese"
-	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -28,10 +25,6 @@ var allowedGroupIDs = []string{
 } // 仅允许这些群咨询股票，留空表示不限制
 
 var watchlistMu sync.Mutex
-var lastPushDateMu sync.Mutex
-var lastPushDate = make(map[string]string)
-var intervalPushMu sync.Mutex
-var lastIntervalPush = make(map[string]time.Time)
 var rateLimitMu sync.Mutex
 var rateLimitHits = make(map[string][]time.Time)
 
@@ -85,6 +78,28 @@ func HandleStockCommand(msg *openwechat.Message) {
 		handleStockIdentity(msg)
 	case strings.HasPrefix(content, "股票限额"):
 		handleStockLimit(msg, strings.TrimSpace(strings.TrimPrefix(content, "股票限额")))
+	case strings.HasPrefix(content, "股票缓存"):
+		handleQuoteCacheStats(msg)
+	case strings.HasPrefix(content, "股票数据源"):
+		handleQuoteProviderHealth(msg)
+	case strings.HasPrefix(content, "股票代码库"):
+		handleSecurityMaster(msg, strings.TrimSpace(strings.TrimPrefix(content, "股票代码库")))
+	case strings.HasPrefix(content, "股票K线"), strings.HasPrefix(content, "股票k线"):
+		handleKLineChart(msg, strings.TrimSpace(content[len("股票K线"):]))
+	case strings.HasPrefix(content, "股票推送时间"):
+		handlePushSlotCommand(msg, strings.TrimSpace(strings.TrimPrefix(content, "股票推送时间")))
+	case strings.HasPrefix(content, "股票周报"):
+		handlePeriodReport(msg, reportWeekly)
+	case strings.HasPrefix(content, "股票月报"):
+		handlePeriodReport(msg, reportMonthly)
+	case strings.HasPrefix(content, "股票任务"):
+		handleScheduledJobs(msg)
+	case strings.HasPrefix(content, "股票日历"):
+		handleTradingCalendar(msg, strings.TrimSpace(strings.TrimPrefix(content, "股票日历")))
+	case strings.HasPrefix(content, "股票分时"):
+		handleMinuteChart(msg, strings.TrimSpace(strings.TrimPrefix(content, "股票分时")))
+	case isMarketIndexCommand(content):
+		handleMarketIndexCommand(msg, strings.TrimSpace(strings.TrimPrefix(content, "股票指数")))
 	case strings.HasPrefix(content, "股票帮助"):
 		replyStockHelp(msg)
 	default:
@@ -92,125 +107,10 @@ func HandleStockCommand(msg *openwechat.Message) {
 	}
 }
 
-// StartDailyWatchlistPush sends daily watchlist overview to subscribed groups.
-func StartDailyWatchlistPush(bot *openwechat.Bot) {
-	ticker := time.NewTicker(time.Minute)
-	go func() {
-		defer ticker.Stop()
-		for range ticker.C {
-			now := time.Now()
-			if now.Format("15:04") != dailyPushTime {
-				continue
-			}
-			store, err := loadWatchlistStore()
-			if err != nil || len(store.Groups) == 0 {
-				continue
-			}
-			self, err := bot.GetCurrentUser()
-			if err != nil {
-				continue
-			}
-			groups, err := self.Groups()
-			if err != nil {
-				continue
-			}
-			for groupID, group := range store.Groups {
-				if !IsAllowedGroupID(groupID) {
-					continue
-				}
-				if !group.Enabled {
-					continue
-				}
-				if !group.Subscribed || len(group.Stocks) == 0 {
-					continue
-				}
-				if pushedToday(groupID, now) {
-					continue
-				}
-				target := groups.SearchByUserName(1, groupID)
-				if target.Count() == 0 {
-					continue
-				}
-				image, err := buildWatchlistOverviewImage(group.Stocks, group.GroupName, "每日收盘")
-				if err == nil {
-					_, _ = target.First().SendImage(bytes.NewReader(image))
-				} else {
-					message := buildWatchlistOverview(group.Stocks, group.GroupName, "每日收盘")
-					_, _ = target.First().SendText(message)
-				}
-				markPushed(groupID, now)
-			}
-		}
-	}()
-}
-
-// StartIntervalWatchlistPush sends interval-based updates for selected stocks.
-func StartIntervalWatchlistPush(bot *openwechat.Bot) {
-	ticker := time.NewTicker(time.Minute)
-	go func() {
-		defer ticker.Stop()
-		for range ticker.C {
-			store, err := loadWatchlistStore()
-			if err != nil || len(store.Groups) == 0 {
-				continue
-			}
-			self, err := bot.GetCurrentUser()
-			if err != nil {
-				continue
-			}
-			groups, err := self.Groups()
-			if err != nil {
-				continue
-			}
-			now := time.Now()
-			for groupID, group := range store.Groups {
-				if !IsAllowedGroupID(groupID) {
-					continue
-				}
-				if !group.Enabled {
-					continue
-				}
-				if len(group.StockIntervals) == 0 {
-					continue
-				}
-				target := groups.SearchByUserName(1, groupID)
-				if target.Count() == 0 {
-					continue
-				}
-				for code, minutes := range group.StockIntervals {
-					if minutes <= 0 {
-						continue
-					}
-					if !shouldIntervalPush(groupID, code, minutes, now) {
-						continue
-					}
-					stock, err := getStockData(code)
-					if err != nil {
-						continue
-					}
-					title := fmt.Sprintf("股票定时提醒（%d分钟）", minutes)
-					indices := fetchMarketIndexSnapshots()
-					image, err := renderWatchlistHTMLImage(title, indices, []*models.StockData{stock}, now.Format("15:04:05"))
-					if err == nil {
-						_, _ = target.First().SendImage(bytes.NewReader(image))
-					} else {
-						message := fmt.Sprintf("%s\n%s\n更新时间：%s",
-							title,
-							formatWatchlistTable([]*models.StockData{stock}),
-							now.Format("15:04:05"))
-						_, _ = target.First().SendText(message)
-					}
-					markIntervalPushed(groupID, code, now)
-				}
-			}
-		}
-	}()
-}
-
 func handleWatchlistAdd(msg *openwechat.Message, args string) {
 	codes := parseStockCodes(args)
 	if len(codes) == 0 {
-		msg.ReplyText("用法：股票添加 600519 / 股票添加 sh600519 sz000001")
+		msg.ReplyText("用法：股票添加 600519 / 股票添加 sh600519 sz000001 hk00700 NVDA")
 		return
 	}
 	groupID, groupName := resolveGroupInfo(msg)
@@ -218,23 +118,24 @@ func handleWatchlistAdd(msg *openwechat.Message, args string) {
 		msg.ReplyText("只支持在群聊中添加关注股票")
 		return
 	}
-	resolved := resolveCodes(codes)
-	if len(resolved) == 0 {
-		msg.ReplyText("没有识别到有效的股票代码")
+	resolution := resolveCodes(codes)
+	if len(resolution.Codes) == 0 {
+		msg.ReplyText(resolution.failureReply("股票添加"))
 		return
 	}
-	added, existed, err := addStocksToWatchlist(groupID, groupName, resolved)
+	added, existed, err := addStocksToWatchlist(groupID, groupName, resolution.Codes)
 	if err != nil {
 		msg.ReplyText(fmt.Sprintf("添加失败：%v", err))
 		return
 	}
 	var parts []string
 	if len(added) > 0 {
-		parts = append(parts, fmt.Sprintf("已添加：%s", strings.Join(added, ", ")))
+		parts = append(parts, fmt.Sprintf("已添加：%s", formatCodesWithNames(added)))
 	}
 	if len(existed) > 0 {
-		parts = append(parts, fmt.Sprintf("已存在：%s", strings.Join(existed, ", ")))
+		parts = append(parts, fmt.Sprintf("已存在：%s", formatCodesWithNames(existed)))
 	}
+	parts = append(parts, resolution.pendingReplies("股票添加")...)
 	msg.ReplyText(strings.Join(parts, "\n"))
 }
 
@@ -249,23 +150,24 @@ func handleWatchlistRemove(msg *openwechat.Message, args string) {
 		msg.ReplyText("只支持在群聊中删除关注股票")
 		return
 	}
-	resolved := resolveCodes(codes)
-	if len(resolved) == 0 {
-		msg.ReplyText("没有识别到有效的股票代码")
+	resolution := resolveCodes(codes)
+	if len(resolution.Codes) == 0 {
+		msg.ReplyText(resolution.failureReply("股票删除"))
 		return
 	}
-	removed, missed, err := removeStocksFromWatchlist(groupID, groupName, resolved)
+	removed, missed, err := removeStocksFromWatchlist(groupID, groupName, resolution.Codes)
 	if err != nil {
 		msg.ReplyText(fmt.Sprintf("删除失败：%v", err))
 		return
 	}
 	var parts []string
 	if len(removed) > 0 {
-		parts = append(parts, fmt.Sprintf("已删除：%s", strings.Join(removed, ", ")))
+		parts = append(parts, fmt.Sprintf("已删除：%s", formatCodesWithNames(removed)))
 	}
 	if len(missed) > 0 {
-		parts = append(parts, fmt.Sprintf("未关注：%s", strings.Join(missed, ", ")))
+		parts = append(parts, fmt.Sprintf("未关注：%s", formatCodesWithNames(missed)))
 	}
+	parts = append(parts, resolution.pendingReplies("股票删除")...)
 	msg.ReplyText(strings.Join(parts, "\n"))
 }
 
@@ -285,7 +187,7 @@ func handleWatchlistList(msg *openwechat.Message) {
 		msg.ReplyText("当前没有关注股票，可用：股票添加 600519")
 		return
 	}
-	msg.ReplyText(fmt.Sprintf("关注列表（%d）：%s", len(group.Stocks), strings.Join(group.Stocks, ", ")))
+	msg.ReplyText(fmt.Sprintf("关注列表（%d）：%s", len(group.Stocks), formatCodesWithNames(group.Stocks)))
 }
 
 func handleWatchlistOverview(msg *openwechat.Message) {
@@ -304,12 +206,13 @@ func handleWatchlistOverview(msg *openwechat.Message) {
 		msg.ReplyText("当前没有关注股票，可用：股票添加 600519")
 		return
 	}
-	image, err := buildWatchlistOverviewImage(group.Stocks, group.GroupName, "当前行情")
+	indices := marketIndicesOf(group)
+	image, err := buildWatchlistOverviewImage(group.Stocks, indices, group.GroupName, "当前行情")
 	if err == nil {
 		_, _ = msg.ReplyImage(bytes.NewReader(image))
 		return
 	}
-	message := buildWatchlistOverview(group.Stocks, group.GroupName, "当前行情")
+	message := buildWatchlistOverview(group.Stocks, indices, group.GroupName, "当前行情")
 	msg.ReplyText(fmt.Sprintf("生成图片失败：%v\n%s", err, message))
 }
 
@@ -324,10 +227,10 @@ func handleWatchlistSubscribe(msg *openwechat.Message, subscribe bool) {
 		return
 	}
 	if subscribe {
-		msg.ReplyText(fmt.Sprintf("已开启每日推送（%s）", dailyPushTime))
+		msg.ReplyText(fmt.Sprintf("已开启定时推送：%s\n调整时间：股票推送时间", formatPushSlots(groupPushSlots(groupID))))
 		return
 	}
-	msg.ReplyText("已关闭每日推送")
+	msg.ReplyText("已关闭定时推送")
 }
 
 func handleWatchlistEnabled(msg *openwechat.Message, enabled bool) {
@@ -350,7 +253,7 @@ func handleWatchlistEnabled(msg *openwechat.Message, enabled bool) {
 func handleWatchlistIntervalSet(msg *openwechat.Message, args string) {
 	fields := strings.Fields(args)
 	if len(fields) < 2 {
-		msg.ReplyText("用法：股票定时 600519 30（单位分钟，0 为关闭）")
+		msg.ReplyText("用法：股票定时 600519 30（单位分钟，0 为关闭，只在交易时段提醒）\n港股、美股休市时也提醒：股票定时 hk00700 30 全天")
 		return
 	}
 	code := strings.TrimSpace(fields[0])
@@ -368,20 +271,26 @@ func handleWatchlistIntervalSet(msg *openwechat.Message, args string) {
 		msg.ReplyText("只支持在群聊中设置定时")
 		return
 	}
-	resolved := resolveCodes([]string{code})
-	if len(resolved) == 0 {
-		msg.ReplyText("没有识别到有效的股票代码")
+	resolution := resolveCodes([]string{code})
+	if len(resolution.Codes) == 0 {
+		msg.ReplyText(resolution.failureReply("股票定时"))
+		return
+	}
+	resolved := resolution.Codes[0]
+	allDay := len(fields) > 2 && fields[2] == "全天"
+	if allDay && stockMarket(resolved) != marketHK && stockMarket(resolved) != marketUS {
+		msg.ReplyText("全天模式仅支持港股、美股，A 股只在交易时段提醒")
 		return
 	}
-	if err := setWatchlistInterval(groupID, groupName, resolved[0], minutes); err != nil {
+	if err := setWatchlistInterval(groupID, groupName, resolved, minutes, allDay); err != nil {
 		msg.ReplyText(fmt.Sprintf("设置失败：%v", err))
 		return
 	}
 	if minutes == 0 {
-		msg.ReplyText(fmt.Sprintf("已关闭 %s 定时提醒", resolved[0]))
+		msg.ReplyText(fmt.Sprintf("已关闭 %s 定时提醒", formatCodeWithName(resolved)))
 		return
 	}
-	msg.ReplyText(fmt.Sprintf("已设置 %s 每 %d 分钟提醒", resolved[0], minutes))
+	msg.ReplyText(fmt.Sprintf("已设置 %s 每 %d 分钟提醒（%s）", formatCodeWithName(resolved), minutes, intervalModeLabel(allDay)))
 }
 
 func handleWatchlistIntervalList(msg *openwechat.Message) {
@@ -405,7 +314,7 @@ func handleWatchlistIntervalList(msg *openwechat.Message) {
 		if minutes <= 0 {
 			continue
 		}
-		lines = append(lines, fmt.Sprintf("%s 每%d分钟", code, minutes))
+		lines = append(lines, fmt.Sprintf("%s 每%d分钟（%s）", formatCodeWithName(code), minutes, intervalModeLabel(group.IntervalAllDay[code])))
 	}
 	if len(lines) == 0 {
 		msg.ReplyText("当前没有定时提醒，可用：股票定时 600519 30")
@@ -441,19 +350,116 @@ func handleStockIdentity(msg *openwechat.Message) {
 		userName, displayName, nickName, remarkName))
 }
 
+func handleQuoteCacheStats(msg *openwechat.Message) {
+	userName := getSenderUserName(msg)
+	if userName == "" || !superAdmins[userName] {
+		msg.ReplyText("仅超管可查看缓存统计")
+		return
+	}
+	stats := GetQuoteCacheStats()
+	total := stats.Hits + stats.Coalesced + stats.Misses
+	saved := 0.0
+	if total > 0 {
+		saved = float64(stats.Hits+stats.Coalesced) / float64(total) * 100
+	}
+	msg.ReplyText(fmt.Sprintf("行情缓存（有效期 %s）：\n命中：%d\n合并等待：%d\n未命中：%d\n上游请求：%d 次\n缓存代码：%d 个\n节省比例：%.1f%%",
+		quoteCacheTTL, stats.Hits, stats.Coalesced, stats.Misses, stats.Upstream, stats.Entries, saved))
+}
+
+func handleQuoteProviderHealth(msg *openwechat.Message) {
+	userName := getSenderUserName(msg)
+	if userName == "" || !superAdmins[userName] {
+		msg.ReplyText("仅超管可查看数据源状态")
+		return
+	}
+	health := GetQuoteProviderHealth()
+	if len(health) == 0 {
+		msg.ReplyText(fmt.Sprintf("当前数据源：%s", currentQuoteProvider().Name()))
+		return
+	}
+	now := time.Now()
+	lines := []string{"行情数据源（按优先级）："}
+	for _, item := range health {
+		status := "正常"
+		if now.Before(item.DownUntil) {
+			status = fmt.Sprintf("冷却中，%s 后重试", item.DownUntil.Format("15:04:05"))
+		} else if item.Failures > 0 {
+			status = fmt.Sprintf("连续失败 %d 次", item.Failures)
+		}
+		line := fmt.Sprintf("- %s：%s", item.Name, status)
+		if !item.LastSuccess.IsZero() {
+			line += fmt.Sprintf("，最近成功 %s", item.LastSuccess.Format("15:04:05"))
+		}
+		if item.Failures > 0 && item.LastError != "" {
+			line += fmt.Sprintf("\n  原因：%s", item.LastError)
+		}
+		lines = append(lines, line)
+	}
+	msg.ReplyText(strings.Join(lines, "\n"))
+}
+
+func handleSecurityMaster(msg *openwechat.Message, args string) {
+	userName := getSenderUserName(msg)
+	if userName == "" || !superAdmins[userName] {
+		msg.ReplyText("仅超管可管理代码库")
+		return
+	}
+	if args == "更新" || args == "刷新" || args == "update" {
+		msg.ReplyText("开始更新代码库，完成后会通知")
+		go func() {
+			master, err := refreshSecurityMaster()
+			if err != nil {
+				msg.ReplyText(fmt.Sprintf("代码库更新失败：%v", err))
+				return
+			}
+			msg.ReplyText(fmt.Sprintf("代码库已更新：共 %d 条（%s）", len(master.Securities), master.Source))
+		}()
+		return
+	}
+	master := securities().master
+	if len(master.Securities) == 0 {
+		msg.ReplyText("本地代码库为空，可用：股票代码库 更新")
+		return
+	}
+	counts := make(map[string]int)
+	delisted := 0
+	for _, security := range master.Securities {
+		if security.Status == securityDelisted {
+			delisted++
+			continue
+		}
+		counts[security.Type]++
+	}
+	msg.ReplyText(fmt.Sprintf("本地代码库：共 %d 条\n股票：%d\n指数：%d\nETF：%d\n已退市：%d\n来源：%s\n更新时间：%s",
+		len(master.Securities),
+		counts[securityTypeStock], counts[securityTypeIndex], counts[securityTypeETF], delisted,
+		master.Source, master.UpdatedAt))
+}
+
 func replyStockHelp(msg *openwechat.Message) {
 	msg.ReplyText("股票功能：\n" +
-		"1) 查询：股票600519 / 股票 sh600519\n" +
+		"1) 查询：股票600519 / 股票 指数000300 / 股票 hk00700 / 股票 AAPL / 股票 茅台\n" +
+		"   多只对比：股票 600519 000001 300750\n" +
 		"2) 添加：股票添加 600519\n" +
 		"3) 删除：股票删除 600519\n" +
 		"4) 列表：股票列表\n" +
 		"5) 波动：股票波动\n" +
 		"6) 订阅：股票订阅 / 股票取消订阅\n" +
-		"7) 定时：股票定时 600519 30\n" +
+		"7) 定时：股票定时 600519 30（交易时段），港股美股可加 全天\n" +
 		"8) 定时列表：股票定时列表\n" +
 		"9) 推送开关：股票开启 / 股票关闭\n" +
 		"10) 身份：股票身份\n" +
-		"11) 限额：股票限额")
+		"11) 限额：股票限额\n" +
+		"12) 缓存统计：股票缓存\n" +
+		"13) 数据源状态：股票数据源\n" +
+		"14) 代码库：股票代码库 / 股票代码库 更新\n" +
+		"15) 指数：股票指数 / 股票指数 设置 沪深300 科创50 恒生指数 / 股票指数 重置\n" +
+		"16) 分时图：股票分时 600519\n" +
+		"17) K线图：股票K线 600519 60 / 股票K线 600519 周K / 股票K线 600519 月K\n" +
+		"18) 交易日历：股票日历\n" +
+		"19) 定时任务：股票任务\n" +
+		"20) 推送时间：股票推送时间 / 股票推送时间 添加 11:31 午盘 / 股票推送时间 删除 11:31\n" +
+		"21) 周报月报：股票周报 / 股票月报（订阅后每周、每月最后一个交易日自动推送）")
 }
 
 // HandleStockHelp replies stock help content.
@@ -492,43 +498,103 @@ func parseStockCodes(args string) []string {
 	return codes
 }
 
-func resolveCodes(codes []string) []string {
-	var resolved []string
+// stockResolution 批量识别结果，名称匹配到多只股票的输入放在 Ambiguous 中等待用户选择，
+// 无法识别的输入放在 Unresolved 中
+type stockResolution struct {
+	Codes      []string
+	Ambiguous  []stockLookup
+	Unresolved []string
+}
+
+func resolveCodes(codes []string) stockResolution {
+	var resolution stockResolution
+	inputs := make(map[string]string)
 	for _, code := range codes {
-		if strings.HasPrefix(code, "sh") || strings.HasPrefix(code, "sz") {
-			resolved = append(resolved, code)
+		lookup := lookupStock(code)
+		if lookup.Code != "" {
+			resolution.Codes = append(resolution.Codes, lookup.Code)
+			inputs[lookup.Code] = code
 			continue
 		}
-		if len(code) == 6 && isNumeric(code) {
-			guess := resolveStockCode(code)
-			if guess != "" {
-				resolved = append(resolved, guess)
-			}
+		if len(lookup.Candidates) > 0 {
+			resolution.Ambiguous = append(resolution.Ambiguous, lookup)
+			continue
 		}
+		resolution.Unresolved = append(resolution.Unresolved, code)
 	}
-	return uniqStrings(resolved)
+	resolution.Codes = uniqStrings(resolution.Codes)
+	resolution.dropMissing(inputs)
+	return resolution
 }
 
-func resolveStockCode(code string) string {
-	url := fmt.Sprintf("http://hq.sinajs.cn/list=sh%s,sz%s", code, code)
-	resp, err := httpGet(url)
-	if err != nil {
-		return ""
+// dropMissing 代码表未收录的代码（如按格式推断的港股、美股代码）用一次批量行情确认是否存在，
+// 没有行情的代码退回为未识别；行情请求失败时无法确认，保留原结果
+func (r *stockResolution) dropMissing(inputs map[string]string) {
+	var unknown []string
+	for _, code := range r.Codes {
+		if lookupSecurity(code) == nil {
+			unknown = append(unknown, code)
+		}
+	}
+	if len(unknown) == 0 {
+		return
+	}
+	stocks, err := fetchQuotes(unknown)
+	if err != nil && len(stocks) == 0 {
+		return
+	}
+	found := make(map[string]bool, len(stocks))
+	for _, stock := range stocks {
+		found[stock.Code] = true
 	}
-	lines := strings.Split(resp, "\n")
-	for _, line := range lines {
-		parts := strings.Split(line, "\"")
-		if len(parts) < 2 {
+	kept := r.Codes[:0]
+	for _, code := range r.Codes {
+		if lookupSecurity(code) != nil || found[code] {
+			kept = append(kept, code)
 			continue
 		}
-		if strings.Contains(line, "sh"+code) && len(parts[1]) > 0 {
-			return "sh" + code
-		}
-		if strings.Contains(line, "sz"+code) && len(parts[1]) > 0 {
-			return "sz" + code
+		r.Unresolved = append(r.Unresolved, inputs[code])
+	}
+	r.Codes = kept
+}
+
+// pendingReplies 需要用户进一步确认或无法识别的输入
+func (r stockResolution) pendingReplies(command string) []string {
+	var replies []string
+	for _, lookup := range r.Ambiguous {
+		replies = append(replies, formatCandidatesReply(lookup, command))
+	}
+	for _, input := range r.Unresolved {
+		replies = append(replies, formatUnresolvedReply(input, suggestSecurities(input)))
+	}
+	return replies
+}
+
+// failureReply 没有任何输入识别成功时的回复
+func (r stockResolution) failureReply(command string) string {
+	replies := r.pendingReplies(command)
+	if len(r.Ambiguous) == 0 {
+		replies = append([]string{"没有识别到有效的股票代码"}, replies...)
+	}
+	return strings.Join(replies, "\n")
+}
+
+// resolveStockCode 优先查本地代码表；未收录时按代码段推断交易所，并用行情确认代码存在，
+// 无法推断时交给行情数据源探测
+func resolveStockCode(code string) string {
+	if resolved := resolveDigitsFromMaster(code); resolved != "" {
+		return resolved
+	}
+	candidates := inferExchanges(code)
+	for _, candidate := range candidates {
+		if _, err := getStockData(candidate); err == nil {
+			return candidate
 		}
 	}
-	return ""
+	if len(candidates) > 0 {
+		return ""
+	}
+	return currentQuoteProvider().ResolveCode(code)
 }
 
 func uniqStrings(values []string) []string {
@@ -647,12 +713,17 @@ func ensureGroupWatchlist(store *models.WatchlistStore, groupID, groupName strin
 			DefaultLimit:   defaultRateLimit,
 			WindowMinutes:  defaultRateWindowMinutes,
 			UserLimits:     make(map[string]int),
+			Indices:        append([]models.MarketIndex(nil), defaultMarketIndices...),
+			PushSlots:      append([]models.PushSlot(nil), defaultPushSlots...),
 		}
 		store.Groups[groupID] = group
 	}
 	if group.StockIntervals == nil {
 		group.StockIntervals = make(map[string]int)
 	}
+	if group.IntervalAllDay == nil {
+		group.IntervalAllDay = make(map[string]bool)
+	}
 	if group.UserLimits == nil {
 		group.UserLimits = make(map[string]int)
 	}
@@ -674,7 +745,7 @@ func loadWatchlistStore() (*models.WatchlistStore, error) {
 	if err != nil {
 		if os.IsNotExist(err) {
 			return &models.WatchlistStore{
-				Version: 3,
+				Version: 6,
 				Groups:  make(map[string]*models.GroupWatchlist),
 			}, nil
 		}
@@ -710,6 +781,34 @@ func loadWatchlistStore() (*models.WatchlistStore, error) {
 		}
 		store.Version = 3
 	}
+	if store.Version < 4 {
+		for _, group := range store.Groups {
+			if len(group.Indices) == 0 {
+				group.Indices = append([]models.MarketIndex(nil), defaultMarketIndices...)
+			}
+		}
+		store.Version = 4
+	}
+	if store.Version < 5 {
+		for _, group := range store.Groups {
+			if group.PushSlots == nil {
+				group.PushSlots = append([]models.PushSlot(nil), defaultPushSlots...)
+			}
+		}
+		store.Version = 5
+	}
+	if store.Version < 6 {
+		// 美股指数改用 gb_$dji 这类代码，gb_dji 查到的是同名个股或空行情
+		legacyIndexCodes := map[string]string{"gb_dji": "gb_$dji", "gb_ixic": "gb_$ixic", "gb_inx": "gb_$inx"}
+		for _, group := range store.Groups {
+			for i, index := range group.Indices {
+				if code, ok := legacyIndexCodes[index.Code]; ok {
+					group.Indices[i].Code = code
+				}
+			}
+		}
+		store.Version = 6
+	}
 	return &store, nil
 }
 
@@ -719,10 +818,15 @@ func saveWatchlistStore(store *models.WatchlistStore) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	// 推送任务随关注列表配置同步
+	syncWatchlistJobs(store)
+	return nil
 }
 
-func setWatchlistInterval(groupID, groupName, code string, minutes int) error {
+func setWatchlistInterval(groupID, groupName, code string, minutes int, allDay bool) error {
 	watchlistMu.Lock()
 	defer watchlistMu.Unlock()
 	store, err := loadWatchlistStore()
@@ -732,8 +836,14 @@ func setWatchlistInterval(groupID, groupName, code string, minutes int) error {
 	group := ensureGroupWatchlist(store, groupID, groupName)
 	if minutes == 0 {
 		delete(group.StockIntervals, code)
+		delete(group.IntervalAllDay, code)
 	} else {
 		group.StockIntervals[code] = minutes
+		if allDay {
+			group.IntervalAllDay[code] = true
+		} else {
+			delete(group.IntervalAllDay, code)
+		}
 	}
 	group.UpdatedAt = time.Now().Format(time.RFC3339)
 	return saveWatchlistStore(store)
@@ -756,15 +866,8 @@ func watchlistFilePath() string {
 	return filepath.Join(".", watchlistFileName)
 }
 
-func buildWatchlistOverview(codes []string, groupName, title string) string {
-	var stocks []*models.StockData
-	for _, code := range codes {
-		stock, err := getStockData(code)
-		if err != nil {
-			continue
-		}
-		stocks = append(stocks, stock)
-	}
+func buildWatchlistOverview(codes []string, indices []models.MarketIndex, groupName, title string) string {
+	stocks := fetchStocksByCodes(codes)
 	head := "股票波动"
 	if groupName != "" {
 		head = fmt.Sprintf("%s - %s", head, groupName)
@@ -772,41 +875,42 @@ func buildWatchlistOverview(codes []string, groupName, title string) string {
 	return fmt.Sprintf("%s（%s）\n%s\n%s\n更新时间：%s",
 		head,
 		title,
-		formatMarketIndexSummary(),
+		formatMarketIndexSummary(indices),
 		formatWatchlistTable(stocks),
 		time.Now().Format("15:04:05"))
 }
 
-func buildWatchlistOverviewImage(codes []string, groupName, title string) ([]byte, error) {
+func buildWatchlistOverviewImage(codes []string, indices []models.MarketIndex, groupName, title string) ([]byte, error) {
 	stocks := fetchStocksByCodes(codes)
-	indices := fetchMarketIndexSnapshots()
+	snapshots := fetchMarketIndexSnapshots(indices)
 	head := "自选行情"
 	fullTitle := fmt.Sprintf("%s（%s）", head, title)
-	return renderWatchlistHTMLImage(fullTitle, indices, stocks, time.Now().Format("15:04:05"))
+	return renderWatchlistHTMLImage(fullTitle, snapshots, stocks, time.Now().Format("15:04:05"))
 }
 
+// fetchStocksByCodes 批量获取行情，获取失败的代码会被跳过
 func fetchStocksByCodes(codes []string) []*models.StockData {
-	var stocks []*models.StockData
-	for _, code := range codes {
-		stock, err := getStockData(code)
-		if err != nil {
-			continue
-		}
-		stocks = append(stocks, stock)
+	stocks, err := fetchQuotes(codes)
+	if err != nil {
+		return nil
 	}
 	return stocks
 }
 
-func fetchMarketIndexSnapshots() []indexSnapshot {
-	snapshots := make([]indexSnapshot, 0, 3)
-	if sh, err := getStockData("sh000001"); err == nil {
-		snapshots = append(snapshots, indexSnapshot{Name: "上证", Stock: sh})
-	}
-	if sz, err := getStockData("sz399001"); err == nil {
-		snapshots = append(snapshots, indexSnapshot{Name: "深证", Stock: sz})
-	}
-	if cyb, err := getStockData("sz399006"); err == nil {
-		snapshots = append(snapshots, indexSnapshot{Name: "创业板", Stock: cyb})
+func fetchMarketIndexSnapshots(indices []models.MarketIndex) []indexSnapshot {
+	codes := make([]string, 0, len(indices))
+	for _, idx := range indices {
+		codes = append(codes, idx.Code)
+	}
+	byCode := make(map[string]*models.StockData)
+	for _, stock := range fetchStocksByCodes(codes) {
+		byCode[stock.Code] = stock
+	}
+	snapshots := make([]indexSnapshot, 0, len(indices))
+	for _, idx := range indices {
+		if stock, ok := byCode[idx.Code]; ok {
+			snapshots = append(snapshots, indexSnapshot{Name: idx.Name, Stock: stock})
+		}
 	}
 	return snapshots
 }
@@ -819,10 +923,18 @@ func formatWatchlistTable(stocks []*models.StockData) string {
 	writer := tabwriter.NewWriter(&buf, 0, 0, 1, ' ', 0)
 	fmt.Fprintln(writer, "代码\t名称\t现价\t涨幅\t涨跌")
 	for _, stock := range stocks {
-		fmt.Fprintf(writer, "%s\t%s\t%.2f\t%+.2f%%\t%+.2f\n",
+		if label := statusLabel(stock); label != "" {
+			fmt.Fprintf(writer, "%s\t%s\t%.2f%s\t%s\t--\n",
+				stock.Code,
+				stock.Name,
+				stock.Price, formatCurrencySuffix(stock.Currency),
+				label)
+			continue
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%.2f%s\t%+.2f%%\t%+.2f\n",
 			stock.Code,
 			stock.Name,
-			stock.Price,
+			stock.Price, formatCurrencySuffix(stock.Currency),
 			stock.ChangePct,
 			stock.Change)
 	}
@@ -830,27 +942,20 @@ func formatWatchlistTable(stocks []*models.StockData) string {
 	return strings.TrimRight(buf.String(), "\n")
 }
 
-func formatMarketIndexSummary() string {
-	sh, err := getStockData("sh000001")
-	if err != nil {
+func formatMarketIndexSummary(indices []models.MarketIndex) string {
+	snapshots := fetchMarketIndexSnapshots(indices)
+	if len(snapshots) < len(indices) {
 		return "大盘指数：获取失败"
 	}
-	sz, err := getStockData("sz399001")
-	if err != nil {
-		return "大盘指数：获取失败"
-	}
-	cyb, err := getStockData("sz399006")
-	if err != nil {
-		return "大盘指数：获取失败"
+	parts := make([]string, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		parts = append(parts, fmt.Sprintf("%s %.2f(%+.2f%%)", snapshot.Name, snapshot.Stock.Price, snapshot.Stock.ChangePct))
 	}
-	return fmt.Sprintf("大盘指数：上证 %.2f(%+.2f%%)  深证 %.2f(%+.2f%%)  创业板 %.2f(%+.2f%%)",
-		sh.Price, sh.ChangePct,
-		sz.Price, sz.ChangePct,
-		cyb.Price, cyb.ChangePct)
+	return "大盘指数：" + strings.Join(parts, "  ")
 }
 
 func shouldEnforceRateLimit(content string) bool {
-	if strings.HasPrefix(content, "股票身份") || strings.HasPrefix(content, "股票帮助") || strings.HasPrefix(content, "股票限额") {
+	if strings.HasPrefix(content, "股票身份") || strings.HasPrefix(content, "股票帮助") || strings.HasPrefix(content, "股票限额") || strings.HasPrefix(content, "股票缓存") || strings.HasPrefix(content, "股票数据源") || strings.HasPrefix(content, "股票代码库") {
 		return false
 	}
 	return true
@@ -1142,61 +1247,3 @@ func clearUserLimit(groupID, groupName, userName string) error {
 	group.UpdatedAt = time.Now().Format(time.RFC3339)
 	return saveWatchlistStore(store)
 }
-
-func pushedToday(groupID string, now time.Time) bool {
-	lastPushDateMu.Lock()
-	defer lastPushDateMu.Unlock()
-	date := now.Format("2006-01-02")
-	if lastPushDate[groupID] == date {
-		return true
-	}
-	return false
-}
-
-func markPushed(groupID string, now time.Time) {
-	lastPushDateMu.Lock()
-	defer lastPushDateMu.Unlock()
-	lastPushDate[groupID] = now.Format("2006-01-02")
-}
-
-func shouldIntervalPush(groupID, code string, minutes int, now time.Time) bool {
-	intervalPushMu.Lock()
-	defer intervalPushMu.Unlock()
-	key := groupID + "|" + code
-	last, ok := lastIntervalPush[key]
-	if !ok {
-		return true
-	}
-	return now.Sub(last) >= time.Duration(minutes)*time.Minute
-}
-
-func markIntervalPushed(groupID, code string, now time.Time) {
-	intervalPushMu.Lock()
-	defer intervalPushMu.Unlock()
-	key := groupID + "|" + code
-	lastIntervalPush[key] = now
-}
-
-func httpGet(url string) (string, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Referer", "https://finance.sina.com.cn")
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-	decoder := simplifiedchinese.GBK.NewDecoder()
-	utf8Body, err := decoder.Bytes(body)
-	if err != nil {
-		return "", err
-	}
-	return string(utf8Body), nil
-}
@@ -13,6 +13,9 @@ import (
 
 const watchlistImageWidth = 1280
 
+// 说明行的高度，与样式中的 .summary 行高一致
+const summaryLineHeight = 30
+
 type watchlistIndexView struct {
 	Name  string
 	Price string
@@ -21,24 +24,32 @@ type watchlistIndexView struct {
 }
 
 type watchlistRowView struct {
-	Code  string
-	Name  string
-	Price string
-	Pct   string
-	Chg   string
-	Class string
+	Code    string
+	Name    string
+	Session string
+	Price   string
+	Pct     string
+	Chg     string
+	Class   string
 }
 
 type watchlistView struct {
 	Title     string
+	Summary   []string // 标题下方的说明行，周报、月报用来展示最佳、最差和相对指数
 	Timestamp string
 	Indices   []watchlistIndexView
 	Rows      []watchlistRowView
 }
 
 func renderWatchlistHTMLImage(title string, indices []indexSnapshot, stocks []*models.StockData, timestamp string) ([]byte, error) {
+	return renderWatchlistReportHTMLImage(title, nil, indices, stocks, timestamp)
+}
+
+// renderWatchlistReportHTMLImage 与 renderWatchlistHTMLImage 相同，标题下多出几行说明
+func renderWatchlistReportHTMLImage(title string, summary []string, indices []indexSnapshot, stocks []*models.StockData, timestamp string) ([]byte, error) {
 	view := watchlistView{
 		Title:     title,
+		Summary:   summary,
 		Timestamp: timestamp,
 		Indices:   buildIndexViews(indices),
 		Rows:      buildRowViews(stocks),
@@ -47,7 +58,7 @@ func renderWatchlistHTMLImage(title string, indices []indexSnapshot, stocks []*m
 	if err != nil {
 		return nil, err
 	}
-	height := estimateWatchlistHeight(len(view.Rows), len(view.Indices))
+	height := estimateWatchlistHeight(len(view.Rows), len(view.Indices)) + int64(len(summary))*summaryLineHeight
 	return renderHTMLToPNG(html, watchlistImageWidth, height)
 }
 
@@ -66,12 +77,16 @@ func renderWatchlistHTML(view watchlistView) (string, error) {
 func buildIndexViews(indices []indexSnapshot) []watchlistIndexView {
 	out := make([]watchlistIndexView, 0, len(indices))
 	for _, idx := range indices {
-		out = append(out, watchlistIndexView{
+		view := watchlistIndexView{
 			Name:  idx.Name,
 			Price: fmt.Sprintf("%.2f", idx.Stock.Price),
 			Pct:   fmt.Sprintf("%+.2f%%", idx.Stock.ChangePct),
 			Class: trendClass(idx.Stock.Change),
-		})
+		}
+		if label := statusLabel(idx.Stock); label != "" {
+			view.Pct = label
+		}
+		out = append(out, view)
 	}
 	return out
 }
@@ -79,18 +94,43 @@ func buildIndexViews(indices []indexSnapshot) []watchlistIndexView {
 func buildRowViews(stocks []*models.StockData) []watchlistRowView {
 	out := make([]watchlistRowView, 0, len(stocks))
 	for _, stock := range stocks {
-		out = append(out, watchlistRowView{
-			Code:  stock.Code,
-			Name:  stock.Name,
-			Price: fmt.Sprintf("%.2f", stock.Price),
-			Pct:   fmt.Sprintf("%+.2f%%", stock.ChangePct),
-			Chg:   fmt.Sprintf("%+.2f", stock.Change),
-			Class: trendClass(stock.Change),
-		})
+		row := watchlistRowView{
+			Code:    stock.Code,
+			Name:    stock.Name,
+			Session: foreignSessionLabel(stock),
+			Price:   fmt.Sprintf("%.2f", stock.Price) + formatCurrencySuffix(stock.Currency),
+			Pct:     fmt.Sprintf("%+.2f%%", stock.ChangePct),
+			Chg:     fmt.Sprintf("%+.2f", stock.Change),
+			Class:   trendClass(stock.Change),
+		}
+		if label := statusLabel(stock); label != "" {
+			row.Pct = label
+			row.Chg = "--"
+		}
+		out = append(out, row)
 	}
 	return out
 }
 
+// foreignSessionLabel 美股与 A 股交易时间不同，行内标注美东行情时间；
+// 日期按 2006-01-02 去掉年份，格式不符时只显示时间
+func foreignSessionLabel(stock *models.StockData) string {
+	if stockMarket(stock.Code) != marketUS {
+		return ""
+	}
+	parts := []string{"美东"}
+	if len(stock.Date) == len("2006-01-02") {
+		parts = append(parts, stock.Date[5:])
+	}
+	if stock.Time != "" {
+		parts = append(parts, stock.Time)
+	}
+	if len(parts) == 1 {
+		return ""
+	}
+	return strings.Join(parts, " ")
+}
+
 func trendClass(change float64) string {
 	if change > 0 {
 		return "up"
@@ -183,6 +223,11 @@ const watchlistHTMLTemplate = `<!DOCTYPE html>
       margin-bottom: 18px;
       flex-wrap: wrap;
     }
+    .summary {
+      font-size: 18px;
+      line-height: 30px;
+      margin-bottom: 14px;
+    }
     .indices span {
       margin-left: 8px;
     }
@@ -206,6 +251,11 @@ const watchlistHTMLTemplate = `<!DOCTYPE html>
     .table tbody tr:nth-child(even) td {
       background: #fbfbfb;
     }
+    .session {
+      margin-left: 10px;
+      font-size: 14px;
+      color: var(--muted);
+    }
     .num { text-align: left; font-variant-numeric: tabular-nums; }
     .up { color: var(--up); }
     .down { color: var(--down); }
@@ -220,6 +270,11 @@ const watchlistHTMLTemplate = `<!DOCTYPE html>
 <body>
   <div class="container">
     <div class="title">{{.Title}}</div>
+    {{if .Summary}}
+    <div class="summary">
+      {{range .Summary}}<div>{{.}}</div>{{end}}
+    </div>
+    {{end}}
     {{if .Indices}}
     <div class="indices">
       <span>大盘：</span>
@@ -243,7 +298,7 @@ const watchlistHTMLTemplate = `<!DOCTYPE html>
           {{range .Rows}}
             <tr>
               <td>{{.Code}}</td>
-              <td>{{.Name}}</td>
+              <td>{{.Name}}{{if .Session}}<span class="session">{{.Session}}</span>{{end}}</td>
               <td class="num">{{.Price}}</td>
               <td class="num {{.Class}}">{{.Pct}}</td>
               <td class="num {{.Class}}">{{.Chg}}</td>
@@ -0,0 +1,24 @@
+package services
+
+import (
+	"github.com/luckfunc/golangBot/internal/models"
+	"testing"
+)
+
+func TestForeignSessionLabel(t *testing.T) {
+	tests := []struct {
+		stock models.StockData
+		want  string
+	}{
+		{models.StockData{Code: "gb_aapl", Date: "2026-10-16", Time: "16:00:00"}, "美东 10-16 16:00:00"},
+		{models.StockData{Code: "gb_aapl", Date: "10-16", Time: "16:00:00"}, "美东 16:00:00"},
+		{models.StockData{Code: "gb_aapl", Time: "16:00:00"}, "美东 16:00:00"},
+		{models.StockData{Code: "gb_aapl"}, ""},
+		{models.StockData{Code: "sh600519", Date: "2026-10-16", Time: "15:00:00"}, ""},
+	}
+	for _, tt := range tests {
+		if got := foreignSessionLabel(&tt.stock); got != tt.want {
+			t.Errorf("foreignSessionLabel(%+v) = %q, want %q", tt.stock, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/eatmoreapple/openwechat"
+	"github.com/luckfunc/golangBot/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 关注列表相关任务的 ID 前缀，关注列表保存后整体重新生成
+const watchlistJobPrefix = "watchlist:"
+
+// 定时推送的补发窗口：推送时间之后这段时间内，仍未推送的群会补发，0 表示不补发
+var dailyPushGrace = envDuration("STOCK_PUSH_GRACE", time.Hour)
+
+// 群列表缓存时间，找不到目标群时会立即刷新
+const groupCacheTTL = 10 * time.Minute
+
+// groupSender 缓存机器人所在的群，避免每个任务都请求一次群列表
+type groupSender struct {
+	mu        sync.Mutex
+	bot       *openwechat.Bot
+	groups    openwechat.Groups
+	fetchedAt time.Time
+}
+
+var pushSender = &groupSender{}
+
+func (g *groupSender) setBot(bot *openwechat.Bot) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.bot = bot
+	g.groups = nil
+}
+
+// find 按群 ID 查找群，缓存过期或未命中时刷新群列表
+func (g *groupSender) find(groupID string) (*openwechat.Group, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.bot == nil {
+		return nil, false
+	}
+	if g.groups != nil && time.Since(g.fetchedAt) < groupCacheTTL {
+		if target := g.groups.SearchByUserName(1, groupID); target.Count() > 0 {
+			return target.First(), true
+		}
+	}
+	self, err := g.bot.GetCurrentUser()
+	if err != nil {
+		return nil, false
+	}
+	groups, err := self.Groups()
+	if err != nil {
+		return nil, false
+	}
+	g.groups = groups
+	g.fetchedAt = time.Now()
+	if target := groups.SearchByUserName(1, groupID); target.Count() > 0 {
+		return target.First(), true
+	}
+	return nil, false
+}
+
+// StartStockScheduler 注册关注列表的推送任务并启动调度器
+func StartStockScheduler(bot *openwechat.Bot) {
+	pushSender.setBot(bot)
+	restorePushState()
+	store, err := loadWatchlistStore()
+	if err != nil {
+		fmt.Println("load watchlist:", err)
+	} else {
+		syncWatchlistJobs(store)
+	}
+	jobScheduler.Start()
+}
+
+// syncWatchlistJobs 按关注列表配置重新生成每日推送和定时提醒任务
+func syncWatchlistJobs(store *models.WatchlistStore) {
+	var jobs []*ScheduledJob
+	catchUpSlots := make(map[string]bool)
+	for groupID, group := range store.Groups {
+		if !IsAllowedGroupID(groupID) || !group.Enabled {
+			continue
+		}
+		if group.Subscribed && len(group.Stocks) > 0 {
+			for _, slot := range pushSlotsOf(group) {
+				jobs = append(jobs, dailyPushJob(groupID, slot))
+				catchUpSlots[slot.Time] = true
+			}
+			jobs = append(jobs, periodReportJob(groupID, reportWeekly), periodReportJob(groupID, reportMonthly))
+			catchUpSlots[periodReportTime] = true
+		}
+		for code, minutes := range group.StockIntervals {
+			if minutes > 0 {
+				jobs = append(jobs, intervalPushJob(groupID, code, minutes, group.IntervalAllDay[code]))
+			}
+		}
+	}
+	if len(catchUpSlots) > 0 && dailyPushGrace > 0 {
+		jobs = append(jobs, dailyCatchUpJob(catchUpSlots))
+	}
+	if err := jobScheduler.ReplaceJobs(watchlistJobPrefix, jobs); err != nil {
+		fmt.Println("sync watchlist jobs:", err)
+	}
+}
+
+// dailyPushJob 群的一个推送时间，交易日按时推送关注列表，标题取推送名称
+func dailyPushJob(groupID string, slot models.PushSlot) *ScheduledJob {
+	return &ScheduledJob{
+		ID:             fmt.Sprintf("%sdaily:%s:%s", watchlistJobPrefix, groupID, slot.Time),
+		GroupID:        groupID,
+		Name:           slot.Name + "推送",
+		Spec:           cronSpecAt(slot.Time),
+		TradingDayOnly: true,
+		Run: func(now time.Time) {
+			runDailyPush(groupID, slot.Time, now)
+		},
+	}
+}
+
+// dailyCatchUpJob 系统任务：补发窗口内每分钟检查一次，给当天还没收到推送的群补发，
+// 覆盖重启、重新登录或获取群列表失败错过推送时间的情况，周报、月报同样补发。
+// slots 为所有群用到的推送时间（含周报、月报时间），没有推送时间处于补发窗口时不读取关注列表
+func dailyCatchUpJob(slots map[string]bool) *ScheduledJob {
+	return &ScheduledJob{
+		ID:             watchlistJobPrefix + "catchup",
+		Name:           "定时推送补发",
+		Spec:           "* * * * *",
+		TradingDayOnly: true,
+		Run: func(now time.Time) {
+			due := false
+			for hhmm := range slots {
+				if inPushGrace(hhmm, now) {
+					due = true
+					break
+				}
+			}
+			if !due {
+				return
+			}
+			store, err := loadWatchlistStore()
+			if err != nil {
+				return
+			}
+			for groupID, group := range store.Groups {
+				if !IsAllowedGroupID(groupID) || !group.Enabled || !group.Subscribed || len(group.Stocks) == 0 {
+					continue
+				}
+				for _, slot := range pushSlotsOf(group) {
+					if inPushGrace(slot.Time, now) {
+						runDailyPush(groupID, slot.Time, now)
+					}
+				}
+				if inPushGrace(periodReportTime, now) {
+					for _, kind := range []string{reportWeekly, reportMonthly} {
+						if isPeriodEnd(kind, now) {
+							runPeriodReport(groupID, kind, now)
+						}
+					}
+				}
+			}
+		},
+	}
+}
+
+// inPushGrace 推送时间已过且仍在补发窗口内
+func inPushGrace(hhmm string, now time.Time) bool {
+	scheduled := pushSlotAt(hhmm, now)
+	return now.After(scheduled) && now.Sub(scheduled) <= dailyPushGrace
+}
+
+// pushSlotAt 推送时间在当天对应的时刻
+func pushSlotAt(hhmm string, now time.Time) time.Time {
+	minute := parseHHMM(hhmm)
+	return time.Date(now.Year(), now.Month(), now.Day(), minute/60, minute%60, 0, 0, now.Location())
+}
+
+// pushSlotKey 推送记录中每个群的每个推送时间（以及周报、月报）各一条
+func pushSlotKey(groupID, hhmm string) string {
+	return groupID + "|" + hhmm
+}
+
+// intervalPushJob 定时提醒默认只在交易时段内从每段开盘起按间隔推送，全天模式按固定间隔推送
+func intervalPushJob(groupID, code string, minutes int, allDay bool) *ScheduledJob {
+	job := &ScheduledJob{
+		ID:      fmt.Sprintf("%sinterval:%s:%s", watchlistJobPrefix, groupID, code),
+		GroupID: groupID,
+		Name:    fmt.Sprintf("%s 每%d分钟提醒（%s）", formatCodeWithName(code), minutes, intervalModeLabel(allDay)),
+	}
+	if allDay {
+		job.Spec = intervalCronSpec(minutes)
+		job.Run = func(now time.Time) {
+			runIntervalPush(groupID, code, minutes, now)
+		}
+		return job
+	}
+	job.Spec = "* * * * *"
+	job.Run = func(now time.Time) {
+		if intervalSlotDue(code, minutes, now) {
+			runIntervalPush(groupID, code, minutes, now)
+		}
+	}
+	job.NextRun = func(after time.Time) time.Time {
+		return nextIntervalSlot(code, minutes, after)
+	}
+	job.Desc = "按交易时段"
+	return job
+}
+
+func intervalModeLabel(allDay bool) string {
+	if allDay {
+		return "全天"
+	}
+	return "交易时段"
+}
+
+// intervalSlotDue 处于交易时段内，且距本段开盘的分钟数是间隔的整数倍
+func intervalSlotDue(code string, minutes int, now time.Time) bool {
+	session, local, ok := currentSession(code, now)
+	if !ok {
+		return false
+	}
+	return (local.Hour()*60+local.Minute()-session.Start)%minutes == 0
+}
+
+// nextIntervalSlot 下一个推送时间：按天取市场当地的交易时段，从每段开盘起按间隔直接算出
+// after 之后的第一个整数倍时刻，最多向后查找两周以覆盖长假
+func nextIntervalSlot(code string, minutes int, after time.Time) time.Time {
+	local, ok := marketLocalTime(code, after)
+	if !ok || minutes <= 0 {
+		return time.Time{}
+	}
+	from := local.Hour()*60 + local.Minute() + 1
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	for i := 0; i < 14; i++ {
+		for _, session := range marketSessionsOn(code, day) {
+			slot := session.Start
+			if from > slot {
+				slot += (from - slot + minutes - 1) / minutes * minutes
+			}
+			if slot < session.End {
+				return time.Date(day.Year(), day.Month(), day.Day(), slot/60, slot%60, 0, 0, day.Location()).In(after.Location())
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+		from = 0
+	}
+	return time.Time{}
+}
+
+// cronSpecAt 把 15:05 这样的时间转换为每天执行的 cron 表达式
+func cronSpecAt(hhmm string) string {
+	minute := parseHHMM(hhmm)
+	return fmt.Sprintf("%d %d * * *", minute%60, minute/60)
+}
+
+// intervalCronSpec 能整除 60 分钟或 24 小时的间隔用步长表示，其它间隔每分钟检查一次距上次推送的时间
+func intervalCronSpec(minutes int) string {
+	switch {
+	case minutes < 60 && 60%minutes == 0:
+		return fmt.Sprintf("*/%d * * * *", minutes)
+	case minutes%60 == 0 && 24%(minutes/60) == 0:
+		return fmt.Sprintf("0 */%d * * *", minutes/60)
+	}
+	return "* * * * *"
+}
+
+func runDailyPush(groupID, slotTime string, now time.Time) {
+	store, err := loadWatchlistStore()
+	if err != nil {
+		return
+	}
+	group := store.Groups[groupID]
+	if group == nil || !group.Enabled || !group.Subscribed || len(group.Stocks) == 0 {
+		return
+	}
+	slot, ok := findPushSlot(group, slotTime)
+	if !ok {
+		return
+	}
+	key := pushSlotKey(groupID, slot.Time)
+	if !claimPush(key, now) {
+		return
+	}
+	target, ok := pushSender.find(groupID)
+	if !ok {
+		releasePush(key, now)
+		return
+	}
+	title := slot.Name
+	if delay := now.Sub(pushSlotAt(slot.Time, now)); delay >= time.Minute {
+		title = fmt.Sprintf("%s 延迟%d分钟", slot.Name, int(delay.Minutes()))
+	}
+	indices := marketIndicesOf(group)
+	image, err := buildWatchlistOverviewImage(group.Stocks, indices, group.GroupName, title)
+	if err == nil {
+		_, err = target.SendImage(bytes.NewReader(image))
+	}
+	if err != nil {
+		message := buildWatchlistOverview(group.Stocks, indices, group.GroupName, title)
+		if _, err := target.SendText(message); err != nil {
+			// 没有送达的群留给补发窗口重试
+			releasePush(key, now)
+			return
+		}
+	}
+}
+
+func runIntervalPush(groupID, code string, minutes int, now time.Time) {
+	if !shouldIntervalPush(groupID, code, minutes, now) {
+		return
+	}
+	target, ok := pushSender.find(groupID)
+	if !ok {
+		return
+	}
+	stock, err := getStockData(code)
+	if err != nil {
+		return
+	}
+	title := fmt.Sprintf("股票定时提醒（%d分钟）", minutes)
+	indices := fetchMarketIndexSnapshots(groupMarketIndices(groupID))
+	image, err := renderWatchlistHTMLImage(title, indices, []*models.StockData{stock}, now.Format("15:04:05"))
+	if err == nil {
+		_, err = target.SendImage(bytes.NewReader(image))
+	}
+	if err != nil {
+		message := fmt.Sprintf("%s\n%s\n更新时间：%s",
+			title,
+			formatWatchlistTable([]*models.StockData{stock}),
+			now.Format("15:04:05"))
+		if _, err := target.SendText(message); err != nil {
+			// 没有送达时不记录，下次检查时重试
+			return
+		}
+	}
+	markIntervalPushed(groupID, code, now)
+}
+
+// handleScheduledJobs 列出本群的定时任务和下次执行时间
+func handleScheduledJobs(msg *openwechat.Message) {
+	groupID, _ := resolveGroupInfo(msg)
+	if groupID == "" {
+		msg.ReplyText("只支持在群聊中查看任务")
+		return
+	}
+	upcoming := jobScheduler.Upcoming(groupID)
+	if len(upcoming) == 0 {
+		msg.ReplyText("本群没有定时任务，可用：股票订阅 / 股票定时 600519 30")
+		return
+	}
+	lines := []string{fmt.Sprintf("本群任务（%d）：", len(upcoming))}
+	for _, item := range upcoming {
+		next := "--"
+		if !item.At.IsZero() {
+			next = formatJobTime(item.At, jobScheduler.clock.Now())
+		}
+		lines = append(lines, fmt.Sprintf("%s｜下次 %s｜%s", item.Job.Name, next, describeJobSpec(item.Job)))
+	}
+	msg.ReplyText(strings.Join(lines, "\n"))
+}
+
+// formatJobTime 今天只显示时间，其它日期带上月日和星期
+func formatJobTime(at, now time.Time) string {
+	if at.Format("2006-01-02") == now.Format("2006-01-02") {
+		return at.Format("15:04")
+	}
+	return at.Format("01-02 ") + weekdayNames[at.Weekday()] + at.Format(" 15:04")
+}
+
+func describeJobSpec(job *ScheduledJob) string {
+	if job.Desc != "" {
+		return job.Desc
+	}
+	if job.TradingDayOnly {
+		return "交易日 " + job.Spec
+	}
+	return job.Spec
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// 逐分钟扫描得到的下一个推送时间，作为 nextIntervalSlot 的对照
+func scanIntervalSlot(code string, minutes int, after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	for limit := t.AddDate(0, 0, 14); t.Before(limit); t = t.Add(time.Minute) {
+		if intervalSlotDue(code, minutes, t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func TestNextIntervalSlot(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip(err)
+	}
+	codes := []string{"sh600519", "hk00700", "gb_aapl"}
+	intervals := []int{1, 7, 30, 45, 90}
+	afters := []string{
+		"2026-10-16 09:00:00", "2026-10-16 09:30:00", "2026-10-16 10:59:30", "2026-10-16 11:29:00",
+		"2026-10-16 12:10:00", "2026-10-16 14:58:00", "2026-10-16 22:15:00", "2026-10-17 02:00:00",
+		// 跨周末和美国夏令时结束（11-01）
+		"2026-10-30 23:00:00", "2026-11-02 21:40:00", "2026-09-30 15:00:00",
+	}
+	for _, code := range codes {
+		for _, minutes := range intervals {
+			for _, value := range afters {
+				after, err := time.ParseInLocation("2006-01-02 15:04:05", value, shanghai)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := scanIntervalSlot(code, minutes, after)
+				if got := nextIntervalSlot(code, minutes, after); !got.Equal(want) {
+					t.Errorf("nextIntervalSlot(%s, %d, %s) = %s, want %s", code, minutes, value, got, want)
+				}
+			}
+		}
+	}
+}